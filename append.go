@@ -0,0 +1,47 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// OpenAppend opens the stream in the file at path, creating it if
+// needed, and returns a writer that appends blocks to it with
+// WithStartOffset set to the size of the file. Close closes the file.
+// With WithMagicHeader, a new file gets a preamble and the preamble of
+// an existing file is checked. Files with an index footer or a stream
+// MAC cannot be appended to, and ErrAppendIndexFooter or
+// ErrAppendStreamMAC is returned for them.
+func OpenAppend(path string, opts ...Option) (*ByteBlockWriter, error) {
+	o := newOptions(opts)
+	if o.indexFooter {
+		return nil, ErrAppendIndexFooter
+	}
+	if o.macKey != nil {
+		return nil, ErrAppendStreamMAC
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err == nil && o.magicHeader && size > 0 {
+		b := make([]byte, preambleSize)
+		if err = readFullAt(f, b, 0); err == ErrNotEnoughBytes {
+			err = ErrBadMagic
+		}
+		if err == nil {
+			err = o.checkPreamble(b)
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	writer := NewByteBlockWriter(f, append(opts[:len(opts):len(opts)], WithStartOffset(size))...)
+	writer.closer = f
+	return writer, nil
+}
+
+var ErrAppendIndexFooter = errors.New("cannot append to a stream with an index footer")
@@ -0,0 +1,47 @@
+package byteblock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenAppend(t *testing.T) {
+	opts := []Option{WithMagicHeader(), WithChecksums()}
+	path := filepath.Join(t.TempDir(), "blocks")
+	var offsets []int64
+	for _, blocks := range [][]string{{"hello"}, {"world", "again"}, nil} {
+		writer, err := OpenAppend(path, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, b := range blocks {
+			writer.WriteString(b, 64)
+			offsets = append(offsets, writer.NextHeaderOffset()-writer.opts.trailerSize()-int64(len(b)))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slicer := NewByteBlockSlicer(data, opts...)
+	for i, expected := range []string{"hello", "world", "again"} {
+		if info, block, err := slicer.SliceInfo(); string(block) != expected || info.DataOffset != offsets[i] || info.DataOffset%64 != 0 || err != nil {
+			t.Errorf("expected %q at %d; got %q, %+v, %v", expected, offsets[i], block, info, err)
+		}
+	}
+	if !slicer.AtEnd() {
+		t.Errorf("expected the end of the stream")
+	}
+
+	if _, err := OpenAppend(path, WithMagicHeader()); err != ErrFormatMismatch {
+		t.Errorf("expected ErrFormatMismatch; got %v", err)
+	}
+	if _, err := OpenAppend(path, WithIndexFooter()); err != ErrAppendIndexFooter {
+		t.Errorf("expected ErrAppendIndexFooter; got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+)
+
+// SliceReverseLinked slices the last block of the stream not sliced
+// yet, using the back links written with WithBackLink, so that
+// repeated calls walk the stream from its end to its start. The slicer
+// must have been created with WithBackLink; otherwise
+// ErrBackLinksDisabled is returned. io.EOF is returned once the
+// backward walk reaches the blocks already sliced by Slice, which
+// conversely stops before the blocks sliced backward.
+func (r *ByteBlockSlicer) SliceReverseLinked() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if !r.opts.backLink {
+		return nil, ErrBackLinksDisabled
+	}
+	end := int64(len(r.data))
+	if end <= r.numBytesSliced {
+		return nil, io.EOF
+	}
+	if end-r.numBytesSliced < r.opts.headerSize()+8 {
+		r.err = ErrNotEnoughBytes
+		return nil, r.err
+	}
+	size := r.opts.readField(r.data[end-8:])
+	if size < r.opts.headerSize()+8 || size > end-r.numBytesSliced {
+		r.err = ErrInvalidBackLink
+		return nil, r.err
+	}
+	// Slice the block forward with a copy limited to it.
+	c := *r
+	c.data = r.data[:end]
+	c.numBytesSliced = end - size
+	c.prevLength = -1
+	c.blocksLeft = -1
+	_, _, data, err := c.slice()
+	if err != nil {
+		r.err = err
+		return nil, r.err
+	}
+	if c.numBytesSliced != end {
+		r.err = ErrInvalidBackLink
+		return nil, r.err
+	}
+	r.maxLength = c.maxLength
+	r.data = r.data[:end-size]
+	return data, nil
+}
+
+var (
+	ErrBackLinksDisabled = errors.New("back links are not enabled")
+	ErrInvalidBackLink   = errors.New("invalid back link")
+)
@@ -0,0 +1,72 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestBackLink(t *testing.T) {
+	blocks := []string{"hello", "", "wonderful", "world", "!"}
+	for _, opts := range [][]Option{
+		{WithBackLink()},
+		{WithBackLink(), WithInlineNames(), WithBlockVersions()},
+		{WithBackLink(), WithFixedStride(64)},
+		{WithBackLink(), WithBigEndian(), WithChecksums()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, b := range blocks {
+			if next := writer.NextHeaderOffset(); next != int64(buf.Len()) {
+				t.Errorf("expected next header at %d; got %d", buf.Len(), next)
+			}
+			writer.WriteString(b, 16)
+		}
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		for i := len(blocks) - 1; i >= 0; i-- {
+			if data, err := slicer.SliceReverseLinked(); string(data) != blocks[i] || err != nil {
+				t.Errorf("expected %q; got %q, %v", blocks[i], data, err)
+			}
+		}
+		if _, err := slicer.SliceReverseLinked(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+
+		// Both directions meet in the middle.
+		slicer = NewByteBlockSlicer(buf.Bytes(), opts...)
+		slicer.Slice()
+		slicer.Slice()
+		for _, expected := range []string{"!", "world", "wonderful"} {
+			if data, err := slicer.SliceReverseLinked(); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+		}
+		if _, err := slicer.SliceReverseLinked(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		if n, err := reader.Drain(); n != len(blocks) || err != nil {
+			t.Errorf("expected %d blocks; got %d, %v", len(blocks), n, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithBackLink())
+	writer.WriteString("hello", 8)
+	data := buf.Bytes()
+	data[len(data)-1] ^= 1
+	if _, err := NewByteBlockSlicer(data, WithBackLink()).SliceReverseLinked(); err != ErrInvalidBackLink {
+		t.Errorf("expected ErrInvalidBackLink; got %v", err)
+	}
+	if _, err := NewByteBlockSlicer(data, WithBackLink()).Slice(); err != ErrInvalidBackLink {
+		t.Errorf("expected ErrInvalidBackLink; got %v", err)
+	}
+	if _, err := NewByteBlockSlicer(data).SliceReverseLinked(); err != ErrBackLinksDisabled {
+		t.Errorf("expected ErrBackLinksDisabled; got %v", err)
+	}
+}
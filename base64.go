@@ -0,0 +1,29 @@
+package byteblock
+
+import (
+	"encoding/base64"
+	"io"
+)
+
+// NewBase64Writer creates a ByteBlockWriter whose output is encoded as
+// standard base64 text before reaching w, for transports that cannot
+// carry binary data. The framing of blocks is unchanged. Close must be
+// called to flush the final base64 quantum.
+func NewBase64Writer(w io.Writer, opts ...Option) *ByteBlockWriter {
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	writer := NewByteBlockWriter(enc, opts...)
+	writer.closer = enc
+	return writer
+}
+
+// NewBase64Slicer decodes text written through a NewBase64Writer and
+// creates a slicer over the decoded stream. Line breaks in text are
+// ignored.
+func NewBase64Slicer(text []byte, opts ...Option) (*ByteBlockSlicer, error) {
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(data, text)
+	if err != nil {
+		return nil, err
+	}
+	return NewByteBlockSlicer(data[:n], opts...), nil
+}
@@ -0,0 +1,42 @@
+package byteblock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"testing"
+)
+
+func TestBase64(t *testing.T) {
+	blocks := []string{"hello", "", "world", "\x00\xff\x10binary"}
+	var buf bytes.Buffer
+	writer := NewBase64Writer(&buf)
+	for _, b := range blocks {
+		if err := writer.WriteString(b, 8); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := base64.StdEncoding.DecodeString(buf.String()); err != nil {
+		t.Errorf("output is not valid base64: %v", err)
+	}
+
+	slicer, err := NewBase64Slicer(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range blocks {
+		if slice, err := slicer.Slice(); string(slice) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, slice, err)
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	if _, err := NewBase64Slicer([]byte("not base64!")); err == nil {
+		t.Errorf("expected error from decoding invalid base64")
+	}
+}
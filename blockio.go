@@ -0,0 +1,161 @@
+package byteblock
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// SliceReader is like Slice except that it returns a reader of the
+// block data, for decoders that consume an io.Reader. The reader
+// shares memory with the slicer as Slice does, and also implements
+// io.ReaderAt and io.Seeker.
+func (r *ByteBlockSlicer) SliceReader() (*bytes.Reader, error) {
+	data, err := r.Slice()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// NextReader reads the header of the next block and returns a reader
+// that streams its data from the underlying reader, so that big blocks
+// need not be held in memory. It returns io.EOF if the stream ends
+// cleanly before the next block. The returned reader reports io.EOF at
+// the end of the block, after verifying the checksums if enabled; with
+// WithCodec, it yields the decompressed data. With WithEncryption, a
+// sealed block is read in memory as a whole to be opened. Any later
+// call on r first skips what is left of the block.
+func (r *ByteBlockReader) NextReader() (io.Reader, error) {
+	h, err := r.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	r.numBlocks++
+	b := &blockReader{r: r, left: h.length}
+	if r.opts.checksums {
+		b.payloadHash = crc32.New(castagnoliTable)
+	}
+	r.block = b
+	if h.length == 0 {
+		// Finish the block now rather than at the first Read.
+		if _, err := b.Read(nil); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if r.opts.inlineNames {
+		if r.err = b.skipName(); r.err != nil {
+			return nil, r.err
+		}
+	}
+	if r.opts.keys != nil && (h.encrypted() || b.left > 0) {
+		sealed, err := io.ReadAll(b)
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.opts.decodeData(h, sealed)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+	if r.opts.codec != CodecNone && h.codec() != CodecNone {
+		zr, err := decompressReader(h.codec(), b, r.opts.maxLength)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	}
+	return b, nil
+}
+
+// skipBlock skips what is left of the block whose reader was returned
+// by NextReader, if any.
+func (r *ByteBlockReader) skipBlock() error {
+	if r.block == nil {
+		return nil
+	}
+	_, err := io.Copy(io.Discard, r.block)
+	return err
+}
+
+// blockReader is the reader of the data of a block returned by
+// ByteBlockReader.NextReader.
+type blockReader struct {
+	r           *ByteBlockReader
+	left        int64       // bytes of data left to read
+	payloadHash hash.Hash32 // checksum of the data, see WithChecksums
+	err         error
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	r := b.r
+	if r.err != nil {
+		b.err = r.err
+		return 0, b.err
+	}
+	if b.left == 0 {
+		var sum uint32
+		if b.payloadHash != nil {
+			sum = b.payloadHash.Sum32()
+		}
+		r.block = nil
+		if r.err = r.finishBlock(sum); r.err != nil {
+			b.err = r.err
+		} else {
+			b.err = io.EOF
+		}
+		return 0, b.err
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := r.reader.Read(p)
+	r.numBytesRead += int64(n)
+	b.left -= int64(n)
+	if r.frameHash != nil {
+		r.frameHash.Write(p[:n])
+	}
+	if b.payloadHash != nil {
+		b.payloadHash.Write(p[:n])
+	}
+	if err == io.EOF && b.left == 0 {
+		err = nil
+	}
+	if err != nil {
+		r.err = readError(err)
+		b.err = r.err
+	}
+	return n, b.err
+}
+
+// skipName reads and drops the inline name at the start of the block
+// data.
+func (b *blockReader) skipName() error {
+	_, err := b.readName()
+	return err
+}
+
+// readName reads the inline name at the start of the block data.
+func (b *blockReader) readName() (string, error) {
+	var prefix [2]byte
+	if b.left < int64(len(prefix)) {
+		return "", ErrNotEnoughBytes
+	}
+	if _, err := io.ReadFull(b, prefix[:]); err != nil {
+		return "", err
+	}
+	nameLength := int64(prefix[0]) | int64(prefix[1])<<8
+	if nameLength > b.left {
+		return "", ErrNotEnoughBytes
+	}
+	name := make([]byte, nameLength)
+	if _, err := io.ReadFull(b, name); err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
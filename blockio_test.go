@@ -0,0 +1,88 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSliceReader(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.WriteString("hello", 8)
+	writer.WriteString("", 8)
+
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range []string{"hello", ""} {
+		br, err := slicer.SliceReader()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data, err := io.ReadAll(br); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	if _, err := slicer.SliceReader(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+}
+
+func TestNextReader(t *testing.T) {
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 100)
+	blocks := [][]byte{text, nil, []byte("short"), text[:1000]}
+	for _, opts := range [][]Option{
+		nil,
+		{WithChecksums(), WithFrameChecksum(), WithBackLink()},
+		{WithInlineNames(), WithFixedStride(8192)},
+		{WithCodec(CodecGzip), WithChecksums()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, block := range blocks {
+			writer.Write(block, 8)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		for i, expected := range blocks {
+			br, err := reader.NextReader()
+			if err != nil {
+				t.Fatalf("block %d: unexpected error: %v", i, err)
+			}
+			if data, err := io.ReadAll(br); !bytes.Equal(data, expected) || err != nil {
+				t.Errorf("block %d: got %d bytes, %v", i, len(data), err)
+			}
+		}
+		if _, err := reader.NextReader(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+
+		// Blocks left partly read are skipped.
+		reader = NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		br, _ := reader.NextReader()
+		br.Read(make([]byte, 10))
+		if data, err := reader.Read(); len(data) != 0 || err != nil {
+			t.Errorf("expected empty block; got %d bytes, %v", len(data), err)
+		}
+		reader.NextReader()
+		if blocks, err := reader.Drain(); blocks != 1 || err != nil {
+			t.Errorf("expected 1 block; got %d, %v", blocks, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithChecksums())
+	writer.Write(text, 8)
+	data := buf.Bytes()
+	data[100] ^= 1
+	br, _ := NewByteBlockReader(bytes.NewReader(data), WithChecksums()).NextReader()
+	if _, err := io.ReadAll(br); err != ErrChecksumMismatch {
+		t.Errorf("expected ErrChecksumMismatch; got %v", err)
+	}
+	br, _ = NewByteBlockReader(bytes.NewReader(data[:200])).NextReader()
+	if _, err := io.ReadAll(br); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
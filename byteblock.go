@@ -11,10 +11,13 @@
 package byteblock
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
+	"hash"
+	"hash/crc32"
 	"io"
-	"reflect"
-	"unsafe"
 )
 
 // ByteBlockWriter writes blocks to a writer specified in
@@ -23,26 +26,147 @@ import (
 // of bytes.
 type ByteBlockWriter struct {
 	writer          io.Writer
+	closer          io.Closer   // closed by Close, if not nil
+	flusher         flusher     // flushed after every block, if not nil
+	payloadHash     hash.Hash32 // checksum of the current block data, see WithChecksums
+	frameHash       hash.Hash32 // checksum of the current block, see WithFrameChecksum
 	numBytesWritten int64
 	numBytesLeft    int64
+	numPaddingBytes int64
+	numBlocks       int64
+	blockPos        int64 // position of the header of the current block
+	inBlock         bool  // whether the current block is not finished
+	declaredBlocks  int64 // -1 if no count header was written
 	err             error
 	stub            [8]byte
+	scratch         []byte        // reused by WriteFunc
+	pending         *pendingBlock // block to compress or seal, see WithCodec
+	index           []IndexEntry  // blocks written so far, see WithIndexFooter
+	deferred        bool          // whether the current block has an unknown length
+	deferredPos     int64         // seek position of its header, see NewBlockUnknownLength
+	tx              *transaction  // current transaction, see Begin
+	mac             hash.Hash     // MAC of the bytes written so far, see WithStreamMAC
+	opts            options
 }
 
 // NewByteBlockWriter creates a ByteBlockWriter that writes to the
 // specified writer. Only one ByteBlockWriter should be created for a
 // given writer to prevent conflicts in writing.
-func NewByteBlockWriter(w io.Writer) *ByteBlockWriter {
-	return &ByteBlockWriter{writer: w}
+func NewByteBlockWriter(w io.Writer, opts ...Option) *ByteBlockWriter {
+	o := newOptions(opts)
+	if g, ok := w.(interface{ Grow(int) }); ok && o.capacity > 0 {
+		g.Grow(int(o.capacity))
+	}
+	writer := &ByteBlockWriter{writer: w, numBytesWritten: o.startOffset, declaredBlocks: -1, opts: o}
+	if o.checksums {
+		writer.payloadHash = crc32.New(castagnoliTable)
+	}
+	if o.indexFooter {
+		writer.index = []IndexEntry{}
+	}
+	if o.frameChecksum {
+		writer.frameHash = crc32.New(castagnoliTable)
+	}
+	if o.macKey != nil {
+		if o.startOffset > 0 {
+			writer.err = ErrAppendStreamMAC
+			return writer
+		}
+		writer.mac = hmac.New(sha256.New, o.macKey)
+	}
+	if o.magicHeader && o.startOffset == 0 {
+		writer.err = writer.rawWrite(encodePreamble(o.format()))
+		// rawWrite counts the preamble against the (nonexistent) block.
+		writer.numBytesLeft = 0
+	}
+	return writer
 }
 
 // NewBlock asks the writer to create a new block with given alignment
 // and length. Non-positive alignments are interpreted as 1-byte
-// aligned. A previous block, if exists, must already have been
-// finished; otherwise ErrNewBlockBeforeFinish is returned. Other
-// errors from previous operations or the underlying writer are also
-// returned.
+// aligned, unless the writer is strict (see WithStrict). A previous
+// block, if exists, must already have been finished; otherwise
+// ErrNewBlockBeforeFinish is returned. Other errors from previous
+// operations or the underlying writer are also returned.
 func (w *ByteBlockWriter) NewBlock(align int64, length int64) error {
+	return w.newBlock(align, length, blockMeta{})
+}
+
+// blockMeta holds the optional metadata stored along with a block.
+type blockMeta struct {
+	name  string // inline name, see WithInlineNames
+	flags uint64 // header flags, see options.hasFlags
+	raw   bool   // whether to skip compression and sealing, see WithCodec
+}
+
+// newBlock is like NewBlock except that it also takes the metadata of
+// the block, which must be empty unless enabled by the options.
+func (w *ByteBlockWriter) newBlock(align int64, length int64, meta blockMeta) error {
+	if err := w.checkNewBlock(length); err != nil {
+		return err
+	}
+	if w.opts.strict && align <= 0 {
+		w.err = ErrInvalidAlign
+		return w.err
+	}
+	if align > 1 && align&(align-1) == 0 && w.opts.baseOffset%align != 0 {
+		w.err = ErrAlignmentUnsatisfiable
+		return w.err
+	}
+	if len(meta.name) > MaxInlineNameLength {
+		w.err = ErrNameTooLong
+		return w.err
+	}
+	if length > 0 && !meta.raw && w.opts.transformsData() && (meta.flags&flagsTransformed == 0 || w.opts.keys != nil && meta.flags&flagsEncrypted == 0) {
+		w.pending = &pendingBlock{align: align, meta: meta, data: make([]byte, 0, length)}
+		w.numBytesLeft = length
+		w.inBlock = true
+		return nil
+	}
+	dataPos := w.numBytesWritten + w.opts.headerSize()
+	if w.opts.offsetMode != offsetAlign {
+		dataPos += w.opts.inlineNameSize(meta.name)
+	}
+	offset := alignOffset(align, dataPos)
+	if w.opts.maxPadding && length > 0 && float64(offset) > w.opts.maxPaddingRatio*float64(length) {
+		w.err = ErrExcessivePadding
+		return w.err
+	}
+	return w.writeHeader(length, align, offset, meta)
+}
+
+// NewBlockAt is like NewBlock except that instead of aligning the
+// data, it places the data exactly at the absolute position dataPos
+// in the stream. ErrImpossibleOffset is returned if dataPos is before
+// the end of the header of the new block. As sealing moves the data
+// after a nonce, ErrEncryptionUnsupported is returned for a non-empty
+// block with WithEncryption.
+func (w *ByteBlockWriter) NewBlockAt(dataPos int64, length int64) error {
+	if err := w.checkNewBlock(length); err != nil {
+		return err
+	}
+	if w.opts.keys != nil && length > 0 {
+		w.err = ErrEncryptionUnsupported
+		return w.err
+	}
+	regionPos := dataPos - w.opts.inlineNameSize("")
+	offset := regionPos - w.numBytesWritten - w.opts.headerSize()
+	if offset < 0 {
+		w.err = ErrImpossibleOffset
+		return w.err
+	}
+	// With the alignment in the header, aligning to regionPos itself
+	// yields exactly the required padding.
+	align := int64(1)
+	if offset > 0 {
+		align = regionPos
+	}
+	return w.writeHeader(length, align, offset, blockMeta{})
+}
+
+// checkNewBlock checks whether a new block of the given length can be
+// created.
+func (w *ByteBlockWriter) checkNewBlock(length int64) error {
 	if w.err != nil {
 		return w.err
 	}
@@ -50,29 +174,297 @@ func (w *ByteBlockWriter) NewBlock(align int64, length int64) error {
 		w.err = ErrNewBlockBeforeFinish
 		return w.err
 	}
-	// Length
-	w.fillStub(int64(length))
-	if w.err = w.rawWrite(w.stub[:]); w.err != nil {
+	if w.opts.countHeader && w.declaredBlocks < 0 {
+		w.err = ErrNoCountHeader
 		return w.err
 	}
-	// Offset
-	offset := int64(alignOffset(align, w.numBytesWritten+8))
-	w.fillStub(offset)
-	if w.err = w.rawWrite(w.stub[:]); w.err != nil {
+	if w.opts.strict && length <= 0 {
+		w.err = ErrInvalidLength
 		return w.err
 	}
+	return nil
+}
+
+// writeHeader writes the header of a new block with the given length,
+// alignment and metadata, followed by offset bytes of padding and, if
+// enabled, the inline name.
+func (w *ByteBlockWriter) writeHeader(length, align, offset int64, meta blockMeta) error {
+	headerPos := w.numBytesWritten
+	nameSize := w.opts.inlineNameSize(meta.name)
+	if w.payloadHash != nil {
+		w.payloadHash.Reset()
+	}
+	if w.frameHash != nil {
+		w.frameHash.Reset()
+	}
+	if stride := w.opts.stride; stride > 0 && w.opts.headerSize()+offset+nameSize > stride-length-w.opts.trailerSize() {
+		w.err = ErrBlockTooLarge
+		return w.err
+	}
+	// Sync marker
+	if w.opts.syncMarkers {
+		if w.err = w.rawWrite(syncMarker[:]); w.err != nil {
+			return w.err
+		}
+	}
+	// Length and offset
+	var fields [2]int64
+	lengthPos, offsetPos := w.opts.fieldPos()
+	lengthPos -= int(w.opts.syncMarkerSize())
+	offsetPos -= int(w.opts.syncMarkerSize())
+	fields[lengthPos/8] = length + nameSize
+	switch w.opts.offsetMode {
+	case offsetAbsolute:
+		fields[offsetPos/8] = headerPos + w.opts.headerSize() + offset
+	case offsetAlign:
+		if align < 1 {
+			align = 1
+		}
+		fields[offsetPos/8] = align
+	default:
+		fields[offsetPos/8] = offset
+	}
+	for _, field := range fields {
+		w.fillField(field)
+		if w.err = w.rawWrite(w.stub[:]); w.err != nil {
+			return w.err
+		}
+	}
+	// Flags
+	if w.opts.hasFlags() {
+		w.fillField(int64(meta.flags))
+		if w.err = w.rawWrite(w.stub[:]); w.err != nil {
+			return w.err
+		}
+	}
 	// Padding
-	if w.err = w.rawWrite(make([]byte, offset)); w.err != nil {
+	if w.err = w.writeZeros(offset); w.err != nil {
 		return w.err
 	}
-	w.numBytesLeft = length
+	w.numPaddingBytes += offset
+	w.numBlocks++
+	if w.index != nil {
+		w.index = append(w.index, IndexEntry{Offset: headerPos, Length: length})
+	}
+	w.numBytesLeft = length + nameSize
+	w.blockPos = headerPos
+	w.inBlock = true
+	// Name
+	if nameSize > 0 {
+		if w.err = w.writeInlineName(meta.name); w.err != nil {
+			return w.err
+		}
+	}
+	if w.numBytesLeft == 0 {
+		return w.finishBlock()
+	}
 	return nil
 }
 
+// writeData writes data to the current block, and finishes the block
+// once it is complete.
+func (w *ByteBlockWriter) writeData(data []byte) error {
+	if w.pending != nil {
+		w.pending.data = append(w.pending.data, data...)
+		w.numBytesLeft -= int64(len(data))
+		if w.numBytesLeft == 0 {
+			return w.flushPending()
+		}
+		return nil
+	}
+	if w.err = w.rawWrite(data); w.err != nil {
+		return w.err
+	}
+	if w.numBytesLeft == 0 && w.inBlock {
+		return w.finishBlock()
+	}
+	return nil
+}
+
+// finishBlock writes whatever follows the data of a complete block.
+func (w *ByteBlockWriter) finishBlock() error {
+	w.inBlock = false
+	if w.opts.stride > 0 {
+		// Trailing padding up to the stride.
+		if w.err = w.writeZeros(w.blockPos + w.opts.stride - w.opts.trailerSize() - w.numBytesWritten); w.err != nil {
+			return w.err
+		}
+	}
+	if w.payloadHash != nil {
+		w.fillField(int64(w.payloadHash.Sum32()))
+		if w.err = w.rawWrite(w.stub[:]); w.err != nil {
+			return w.err
+		}
+	}
+	if w.frameHash != nil {
+		w.fillField(int64(w.frameHash.Sum32()))
+		if w.err = w.rawWrite(w.stub[:]); w.err != nil {
+			return w.err
+		}
+	}
+	if w.opts.backLink {
+		w.fillField(w.numBytesWritten + 8 - w.blockPos)
+		if w.err = w.rawWrite(w.stub[:]); w.err != nil {
+			return w.err
+		}
+	}
+	// rawWrite counts anything written after the data against the
+	// block.
+	w.numBytesLeft = 0
+	if w.flusher != nil {
+		w.err = w.flusher.Flush()
+	}
+	return w.err
+}
+
+// flusher is implemented by buffered writers such as *bufio.Writer.
+type flusher interface {
+	Flush() error
+}
+
+// errorCloser is implemented by closers that can be told why the
+// stream ends, such as *io.PipeWriter.
+type errorCloser interface {
+	CloseWithError(err error) error
+}
+
+// WriteCountHeader writes n as the number of blocks that follow, so
+// that readers can learn it upfront with ReadCount. The writer must
+// have been created with WithCountHeader; otherwise
+// ErrCountHeaderDisabled is returned. It must be called before
+// anything else is written, and only once; otherwise
+// ErrCountHeaderNotFirst is returned. Close then checks that exactly n
+// blocks have been written.
+func (w *ByteBlockWriter) WriteCountHeader(n int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.opts.countHeader {
+		w.err = ErrCountHeaderDisabled
+		return w.err
+	}
+	if w.declaredBlocks >= 0 || w.numBytesWritten != w.opts.preambleSize()-countHeaderSize {
+		w.err = ErrCountHeaderNotFirst
+		return w.err
+	}
+	w.fillStub(n)
+	w.err = w.rawWrite(w.stub[:])
+	// rawWrite counts the count header against the (nonexistent) block.
+	w.numBytesLeft = 0
+	if w.err != nil {
+		return w.err
+	}
+	w.declaredBlocks = n
+	return nil
+}
+
+// Close finishes the stream. It returns ErrBlockNotFinished if the
+// current block is not finished, ErrTransactionActive if a transaction
+// is not committed or rolled back, and ErrCountMismatch if the number
+// of blocks written differs from the one given to WriteCountHeader, or
+// ErrNoCountHeader if none was given with WithCountHeader.
+// Writing after Close fails with ErrWriterClosed. Close does not close
+// the writer given at construction, but it does flush any encoding
+// layer the writer adds on top of it (see NewBase64Writer). If Close
+// fails, the reader of NewPipe gets the same error rather than waiting
+// for the rest of the stream, and the writer stays failed.
+func (w *ByteBlockWriter) Close() error {
+	if err := w.finish(); err != nil {
+		if c, ok := w.closer.(errorCloser); ok {
+			if w.err == nil {
+				w.err = err
+			}
+			w.closer = nil
+			c.CloseWithError(err)
+		}
+		return err
+	}
+	if w.closer != nil {
+		closer := w.closer
+		w.closer = nil
+		if w.err = closer.Close(); w.err != nil {
+			return w.err
+		}
+	}
+	w.err = ErrWriterClosed
+	return nil
+}
+
+// finish is like Close except that it leaves the closer alone.
+func (w *ByteBlockWriter) finish() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.numBytesLeft > 0 {
+		return ErrBlockNotFinished
+	}
+	if w.tx != nil {
+		return ErrTransactionActive
+	}
+	if w.opts.countHeader && w.declaredBlocks < 0 {
+		w.err = ErrNoCountHeader
+		return w.err
+	}
+	if w.declaredBlocks >= 0 && w.declaredBlocks != w.numBlocks {
+		w.err = ErrCountMismatch
+		return w.err
+	}
+	if w.index != nil {
+		if w.err = w.writeIndexFooter(); w.err != nil {
+			return w.err
+		}
+	}
+	if w.mac != nil {
+		if w.err = w.writeMAC(); w.err != nil {
+			return w.err
+		}
+	}
+	if w.flusher != nil {
+		if w.err = w.flusher.Flush(); w.err != nil {
+			return w.err
+		}
+	}
+	return nil
+}
+
+// NextHeaderOffset returns the position in the stream where the
+// header of the next block will be written. If the current block is
+// not finished yet, this is the position right after its end.
+func (w *ByteBlockWriter) NextHeaderOffset() int64 {
+	if !w.inBlock {
+		return w.numBytesWritten
+	}
+	if w.opts.stride > 0 {
+		return w.blockPos + w.opts.stride
+	}
+	return w.numBytesWritten + w.numBytesLeft + w.opts.trailerSize()
+}
+
+// PaddingBytes returns the total number of padding bytes written for
+// alignment since construction.
+func (w *ByteBlockWriter) PaddingBytes() int64 {
+	return w.numPaddingBytes
+}
+
 // Append appends a chunk of data to the current block. The length of
 // data must not exceed the number of bytes left for the current
-// block.
+// block. If the writer was created with WithAutoBlock and the current
+// block is already finished, Append first starts a new block of
+// exactly len(data) bytes.
 func (w *ByteBlockWriter) Append(data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.opts.autoBlock && w.numBytesLeft == 0 {
+		if w.err = w.NewBlock(w.opts.autoBlockAlign, int64(len(data))); w.err != nil {
+			return w.err
+		}
+	}
+	return w.append(data)
+}
+
+// append is like Append but never starts a new block.
+func (w *ByteBlockWriter) append(data []byte) error {
 	if w.err != nil {
 		return w.err
 	}
@@ -81,23 +473,131 @@ func (w *ByteBlockWriter) Append(data []byte) error {
 		w.err = ErrWriteMoreThanRequested
 		return w.err
 	}
-	if w.err = w.rawWrite(data); w.err != nil {
-		return w.err
-	}
-	return nil
+	return w.writeData(data)
 }
 
 // AppendString is like Append() except that it takes a string.
 func (w *ByteBlockWriter) AppendString(data string) error {
 	// Because Append() does not modify data, we can temporary fake a
-	// byte slice out of data.
-	var dataBytes []byte
-	stringHeader := (*reflect.StringHeader)(unsafe.Pointer(&data))
-	bytesHeader := (*reflect.SliceHeader)(unsafe.Pointer(&dataBytes))
-	bytesHeader.Data = stringHeader.Data
-	bytesHeader.Len = stringHeader.Len
-	bytesHeader.Cap = stringHeader.Len
-	return w.Append(dataBytes)
+	// byte slice out of data, unless built with byteblock_nounsafe.
+	return w.Append(stringBytes(data))
+}
+
+// AppendStringv appends the given strings in order to the current
+// block. The total length of parts must not exceed the number of bytes
+// left for the current block; this is checked before anything is
+// written.
+func (w *ByteBlockWriter) AppendStringv(parts ...string) error {
+	if w.err != nil {
+		return w.err
+	}
+	var length int64
+	for _, p := range parts {
+		length += int64(len(p))
+	}
+	if length > w.numBytesLeft {
+		w.err = ErrWriteMoreThanRequested
+		return w.err
+	}
+	for _, p := range parts {
+		if w.err = w.writeData(stringBytes(p)); w.err != nil {
+			return w.err
+		}
+	}
+	return nil
+}
+
+// ReadFrom implements io.ReaderFrom. It copies bytes from r into the
+// current block until either the block is complete or r reaches
+// EOF. It returns the number of bytes copied; reaching EOF is not an
+// error, even if the block is not complete yet. As Write takes an
+// alignment, ByteBlockWriter is not an io.Writer: io.Copy reaches
+// ReadFrom through the writer returned by NewBlockWriter instead.
+func (w *ByteBlockWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.pending != nil {
+		buf := bytes.NewBuffer(w.pending.data)
+		n, w.err = io.Copy(buf, io.LimitReader(r, w.numBytesLeft))
+		w.pending.data = buf.Bytes()
+		w.numBytesLeft -= n
+		if w.err == nil && w.numBytesLeft == 0 {
+			w.err = w.flushPending()
+		}
+		return n, w.err
+	}
+	// Copy straight into the underlying writer so that io.Copy can
+	// use whatever fast path it offers.
+	dst := w.writer
+	if w.payloadHash != nil {
+		dst = io.MultiWriter(dst, w.payloadHash)
+	}
+	if w.frameHash != nil {
+		dst = io.MultiWriter(dst, w.frameHash)
+	}
+	if w.mac != nil && w.tx == nil {
+		dst = io.MultiWriter(dst, w.mac)
+	}
+	n, w.err = io.Copy(dst, io.LimitReader(r, w.numBytesLeft))
+	w.numBytesWritten += n
+	w.numBytesLeft -= n
+	if w.err == nil && w.numBytesLeft == 0 && w.inBlock {
+		w.err = w.finishBlock()
+	}
+	return n, w.err
+}
+
+// AppendFrom copies exactly n bytes from r into the current block,
+// without the caller having to hold them in memory, like ReadFrom
+// does. n must not exceed the number of bytes left for the current
+// block; otherwise ErrWriteMoreThanRequested is returned. If r ends
+// before n bytes, io.ErrUnexpectedEOF is returned, and the bytes
+// copied so far remain in the block.
+func (w *ByteBlockWriter) AppendFrom(r io.Reader, n int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if n < 0 || n > w.numBytesLeft {
+		w.err = ErrWriteMoreThanRequested
+		return w.err
+	}
+	m, err := w.ReadFrom(io.LimitReader(r, n))
+	if err != nil {
+		return err
+	}
+	if m < n {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// NewBlockWriter starts a new block like NewBlock, and returns an
+// io.Writer restricted to it, so that encoders such as gzip.Writer or
+// json.Encoder can produce the block data directly. Writing more than
+// length bytes in total fails with ErrWriteMoreThanRequested. The
+// returned writer must not be used once the block is complete.
+func (w *ByteBlockWriter) NewBlockWriter(align int64, length int64) (io.Writer, error) {
+	if err := w.NewBlock(align, length); err != nil {
+		return nil, err
+	}
+	return blockWriter{w}, nil
+}
+
+// blockWriter is the io.Writer returned by NewBlockWriter.
+type blockWriter struct {
+	w *ByteBlockWriter
+}
+
+func (b blockWriter) Write(data []byte) (int, error) {
+	if err := b.w.append(data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (b blockWriter) ReadFrom(r io.Reader) (int64, error) {
+	return b.w.ReadFrom(r)
 }
 
 // Write is a convenience method that creates a block out of the given
@@ -106,38 +606,163 @@ func (w *ByteBlockWriter) Write(data []byte, align int64) error {
 	if w.err = w.NewBlock(align, int64(len(data))); w.err != nil {
 		return w.err
 	}
-	if w.err = w.Append(data); w.err != nil {
+	if w.err = w.append(data); w.err != nil {
 		return w.err
 	}
 	return nil
 }
 
+// WriteSplit writes data as n contiguous blocks with the given
+// alignment. Each block has len(data)/n bytes, except the last one,
+// which also takes the remainder. ErrInvalidSplit is returned if n is
+// not positive.
+func (w *ByteBlockWriter) WriteSplit(data []byte, n int, align int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if n <= 0 {
+		w.err = ErrInvalidSplit
+		return w.err
+	}
+	size := len(data) / n
+	for i := 0; i < n-1; i++ {
+		if err := w.Write(data[:size], align); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+	return w.Write(data, align)
+}
+
+// WriteFunc writes a block of the given length and alignment whose
+// data is produced by fill. fill is given a buffer of exactly length
+// bytes to fill in place; the buffer is reused by later calls, so fill
+// must not retain it. If fill returns an error, nothing is written and
+// the error is returned.
+func (w *ByteBlockWriter) WriteFunc(length, align int64, fill func(buf []byte) error) error {
+	if w.err != nil {
+		return w.err
+	}
+	if length < 0 {
+		w.err = ErrInvalidLength
+		return w.err
+	}
+	if int64(cap(w.scratch)) < length {
+		w.scratch = make([]byte, length)
+	}
+	buf := w.scratch[:length]
+	if err := fill(buf); err != nil {
+		return err
+	}
+	return w.Write(buf, align)
+}
+
+// WriteLoop repeatedly calls next and writes the returned data as a
+// block with the returned alignment, until next returns ok == false
+// or an error. The first error from either next or the writer is
+// returned.
+func (w *ByteBlockWriter) WriteLoop(next func() (data []byte, align int64, ok bool, err error)) error {
+	for {
+		data, align, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if err := w.Write(data, align); err != nil {
+			return err
+		}
+	}
+}
+
 // WriteString is like Write() except that it takes a string.
 func (w *ByteBlockWriter) WriteString(data string, align int64) error {
 	if w.err = w.NewBlock(align, int64(len(data))); w.err != nil {
 		return w.err
 	}
-	if w.err = w.AppendString(data); w.err != nil {
+	if w.err = w.append(stringBytes(data)); w.err != nil {
 		return w.err
 	}
 	return nil
 }
 
+// Sync commits everything written so far to stable storage. The
+// current block must be finished; otherwise ErrBlockNotFinished is
+// returned. If the underlying writer has a Flush() error method, it
+// is called first. If it has a Sync() error method (as *os.File does),
+// it is called next; otherwise Sync does nothing more.
+func (w *ByteBlockWriter) Sync() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.numBytesLeft > 0 {
+		return ErrBlockNotFinished
+	}
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		if w.err = f.Flush(); w.err != nil {
+			return w.err
+		}
+	}
+	if s, ok := w.writer.(interface{ Sync() error }); ok {
+		if w.err = s.Sync(); w.err != nil {
+			return w.err
+		}
+	}
+	return nil
+}
+
 func (w *ByteBlockWriter) fillStub(n int64) {
 	fillInt64(n, w.stub[:])
 }
 
+// fillField is like fillStub except that it fills a field of a block
+// header or trailer, see WithBigEndian.
+func (w *ByteBlockWriter) fillField(n int64) {
+	w.opts.fillField(n, w.stub[:])
+}
+
 // rawWrite writes the given data to the underlying writer and updates
 // numBytesWritten and numBytesLeft. However it does not check whether
 // its updates are valid (especially for numBytesLeft), which is its
 // caller's responsibility.
 func (w *ByteBlockWriter) rawWrite(data []byte) error {
 	n, err := w.writer.Write(data)
+	if w.payloadHash != nil && w.numBytesLeft > 0 {
+		// Only block data is written while bytes are left.
+		w.payloadHash.Write(data[:n])
+	}
+	if w.frameHash != nil {
+		w.frameHash.Write(data[:n])
+	}
+	if w.mac != nil && w.tx == nil {
+		// Transactions feed the MAC at Commit.
+		w.mac.Write(data[:n])
+	}
 	w.numBytesWritten += int64(n)
 	w.numBytesLeft -= int64(n)
 	return err
 }
 
+// castagnoliTable is the table of the CRC-32C checksums of blocks.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// zeros is written in chunks as padding, so that padding does not
+// allocate however large the alignment is.
+var zeros [4096]byte
+
+// writeZeros writes n bytes of zeros to the underlying writer.
+func (w *ByteBlockWriter) writeZeros(n int64) error {
+	for n > 0 {
+		chunk := zeros[:min(n, int64(len(zeros)))]
+		if err := w.rawWrite(chunk); err != nil {
+			return err
+		}
+		n -= int64(len(chunk))
+	}
+	return nil
+}
+
 // alignOffset computes the amount of padding needed to start at a
 // position that is a multiple of align from pos.
 func alignOffset(align, pos int64) int64 {
@@ -154,6 +779,19 @@ func alignOffset(align, pos int64) int64 {
 var (
 	ErrNewBlockBeforeFinish   = errors.New("creating new block before finishing the previous one")
 	ErrWriteMoreThanRequested = errors.New("writing more bytes than requested")
+	ErrInvalidLength          = errors.New("invalid block length")
+	ErrInvalidAlign           = errors.New("invalid block alignment")
+	ErrBlockNotFinished       = errors.New("current block is not finished")
+	ErrImpossibleOffset       = errors.New("data position is before the end of the header")
+	ErrCountHeaderNotFirst    = errors.New("count header must come first")
+	ErrCountMismatch          = errors.New("number of blocks differs from the count header")
+	ErrCountHeaderDisabled    = errors.New("count header is not enabled")
+	ErrNoCountHeader          = errors.New("count header must be written before any block")
+	ErrWriterClosed           = errors.New("writer is closed")
+	ErrBlockTooLarge          = errors.New("block does not fit in the stride")
+	ErrExcessivePadding       = errors.New("alignment padding exceeds the maximum ratio")
+	ErrInvalidSplit           = errors.New("invalid number of blocks to split into")
+	ErrAlignmentUnsatisfiable = errors.New("alignment is not satisfiable from the base offset")
 )
 
 // ByteBlockSlicer slices a byte slice specified at construction into
@@ -161,50 +799,304 @@ var (
 // ByteBlockWriter.
 type ByteBlockSlicer struct {
 	data           []byte
+	basePos        int64 // position of data in the whole stream
 	numBytesSliced int64
-	err            error
+	numBlocks      int64 // index of the next block, see SliceInfo
+	// prevLength is the length of the previous block for
+	// WithOrderCheck, or -1 if there is none.
+	prevLength int64
+	maxLength  int64
+	blocksLeft int64 // -1 if not limited by LimitBlocks
+	count      int64 // see WithCountHeader, or -1 if unreadable
+	err        error
+	opts       options
 }
 
 // NewByteBlockSlicer creates a new slicer with the given backing data
 // slice.
-func NewByteBlockSlicer(data []byte) *ByteBlockSlicer {
-	return &ByteBlockSlicer{data: data}
+func NewByteBlockSlicer(data []byte, opts ...Option) *ByteBlockSlicer {
+	return newSlicer(data, newOptions(opts))
+}
+
+// newSlicer is like NewByteBlockSlicer except that it takes the
+// options already applied.
+func newSlicer(data []byte, o options) *ByteBlockSlicer {
+	r := &ByteBlockSlicer{data: data, prevLength: -1, blocksLeft: -1, opts: o}
+	if r.opts.countHeader && len(data) > 0 {
+		r.count = -1 // until read below
+	}
+	if r.opts.macKey != nil {
+		macPos, err := r.opts.checkMAC(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			r.err = err
+		}
+		r.data = data[:macPos]
+	}
+	if r.opts.indexFooter && r.err == nil {
+		indexPos, _, err := r.opts.loadIndex(bytes.NewReader(r.data), int64(len(r.data)))
+		if err != nil {
+			r.err = err
+		}
+		r.data = r.data[:indexPos]
+	}
+	if r.opts.magicHeader && r.err == nil && len(r.data) > 0 {
+		if r.err = r.opts.checkPreamble(r.data); r.err == nil {
+			r.data = r.data[preambleSize:]
+			r.basePos = preambleSize
+		}
+	}
+	if r.opts.countHeader && r.err == nil && len(data) > 0 {
+		if len(r.data) < countHeaderSize {
+			r.err = ErrNotEnoughBytes
+			return r
+		}
+		r.count = readInt64(r.data)
+		r.data = r.data[countHeaderSize:]
+		r.basePos += countHeaderSize
+	}
+	return r
 }
 
 // Slice returns the next data block, sliced out of the backing data
 // slice.
 func (r *ByteBlockSlicer) Slice() (data []byte, err error) {
+	_, _, data, err = r.slice()
+	return data, err
+}
+
+// SliceString is like Slice except that it returns the block data as
+// a string, which shares memory with the slicer without copying. The
+// backing data slice must therefore not be modified while the string
+// is in use. With the byteblock_nounsafe build tag, the data is
+// copied instead.
+func (r *ByteBlockSlicer) SliceString() (string, error) {
+	data, err := r.Slice()
+	if err != nil {
+		return "", err
+	}
+	return bytesString(data), nil
+}
+
+// slice returns the next data block, along with its header and its
+// inline name if inline names are enabled.
+func (r *ByteBlockSlicer) slice() (h header, name, data []byte, err error) {
+	return r.sliceBlock(true)
+}
+
+// sliceBlock is like slice, except that unless payload is true, it
+// leaves the block data alone: it neither verifies the checksum of the
+// data, nor splits the inline name, nor decompresses the data, nor
+// checks the order, and it returns the raw data.
+func (r *ByteBlockSlicer) sliceBlock(payload bool) (h header, name, data []byte, err error) {
 	if r.err != nil {
-		return nil, r.err
+		return header{}, nil, nil, r.err
 	}
-	if r.numBytesSliced >= int64(len(r.data)) {
-		return nil, io.EOF
+	if r.numBytesSliced >= int64(len(r.data)) || r.blocksLeft == 0 {
+		return header{}, nil, nil, io.EOF
 	}
-	var b []byte
-	// Length
-	b, r.err = r.rawSlice(8)
-	if r.err != nil {
-		return nil, r.err
+	// Header
+	headerPos := r.numBytesSliced
+	b, err := r.rawSlice(r.opts.headerSize())
+	if err != nil {
+		r.err = err
+		return header{}, nil, nil, r.err
 	}
-	length := readInt64(b)
-	// Offset
-	b, r.err = r.rawSlice(8)
-	if r.err != nil {
-		return nil, r.err
+	if h, r.err = r.opts.decodeHeader(b, r.basePos+headerPos); r.err != nil {
+		return header{}, nil, nil, r.err
 	}
-	offset := readInt64(b)
 	// Padding
-	if _, r.err = r.rawSlice(offset); r.err != nil {
-		return nil, r.err
+	if _, r.err = r.rawSlice(h.padding); r.err != nil {
+		return header{}, nil, nil, r.err
 	}
 	// Data
-	return r.rawSlice(length)
+	if data, r.err = r.rawSlice(h.length); r.err != nil {
+		return header{}, nil, nil, r.err
+	}
+	// Trailing padding
+	if stride := r.opts.stride; stride > 0 {
+		if r.numBytesSliced-headerPos > stride-r.opts.trailerSize() {
+			r.err = ErrBlockTooLarge
+			return header{}, nil, nil, r.err
+		}
+		if _, r.err = r.rawSlice(headerPos + stride - r.opts.trailerSize() - r.numBytesSliced); r.err != nil {
+			return header{}, nil, nil, r.err
+		}
+	}
+	// Checksum
+	if r.opts.checksums {
+		var b []byte
+		if b, r.err = r.rawSlice(8); r.err != nil {
+			return header{}, nil, nil, r.err
+		}
+		if payload && r.opts.readField(b) != int64(crc32.Checksum(data, castagnoliTable)) {
+			r.err = ErrChecksumMismatch
+			return header{}, nil, nil, r.err
+		}
+	}
+	// Frame checksum
+	if r.opts.frameChecksum {
+		frame := r.data[headerPos:r.numBytesSliced]
+		var b []byte
+		if b, r.err = r.rawSlice(8); r.err != nil {
+			return header{}, nil, nil, r.err
+		}
+		if payload && r.opts.readField(b) != int64(crc32.Checksum(frame, castagnoliTable)) {
+			r.err = ErrFrameChecksumMismatch
+			return header{}, nil, nil, r.err
+		}
+	}
+	// Back link
+	if r.opts.backLink {
+		var b []byte
+		if b, r.err = r.rawSlice(8); r.err != nil {
+			return header{}, nil, nil, r.err
+		}
+		if r.opts.readField(b) != r.numBytesSliced-headerPos {
+			r.err = ErrInvalidBackLink
+			return header{}, nil, nil, r.err
+		}
+	}
+	if !payload {
+		if r.opts.blockKinds && h.kind() == KindEnd {
+			r.err = io.EOF
+			return header{}, nil, nil, r.err
+		}
+		if r.blocksLeft > 0 {
+			r.blocksLeft--
+		}
+		r.numBlocks++
+		return h, nil, data, nil
+	}
+	if r.opts.inlineNames {
+		if name, data, r.err = splitInlineName(data); r.err != nil {
+			return header{}, nil, nil, r.err
+		}
+	}
+	if r.opts.transformsData() {
+		// The block is consumed even if it fails to decode.
+		if data, err = r.opts.decodeData(h, data); err != nil {
+			return header{}, nil, nil, err
+		}
+	}
+	if r.opts.blockKinds && h.kind() == KindEnd {
+		r.err = io.EOF
+		return header{}, nil, nil, r.err
+	}
+	if less := r.opts.orderCheck; less != nil {
+		length := int64(len(data))
+		if r.prevLength >= 0 && !less(r.prevLength, length) {
+			r.err = ErrOrderViolation
+			return header{}, nil, nil, r.err
+		}
+		r.prevLength = length
+	}
+	if int64(len(data)) > r.maxLength {
+		r.maxLength = int64(len(data))
+	}
+	if r.blocksLeft > 0 {
+		r.blocksLeft--
+	}
+	r.numBlocks++
+	return h, name, data, nil
+}
+
+// SliceStride slices block index of a stream written with
+// WithFixedStride, which starts at index*stride, regardless of the
+// current position and any previous error. Later calls to Slice
+// continue with the block after it. ErrIndexOutOfRange is returned if
+// there is no such block; ErrStrideDisabled is returned if the slicer
+// was not created with WithFixedStride.
+func (r *ByteBlockSlicer) SliceStride(index int64) ([]byte, error) {
+	stride := r.opts.stride
+	if stride <= 0 {
+		return nil, ErrStrideDisabled
+	}
+	if index < 0 || index >= int64(len(r.data))/stride {
+		return nil, ErrIndexOutOfRange
+	}
+	r.numBytesSliced = index * stride
+	r.numBlocks = index
+	r.prevLength = -1
+	r.err = nil
+	return r.Slice()
+}
+
+// ReadCount returns the number of blocks written by
+// ByteBlockWriter.WriteCountHeader, which can be called at any time.
+// The slicer must have been created with WithCountHeader; otherwise
+// ErrCountHeaderDisabled is returned.
+func (r *ByteBlockSlicer) ReadCount() (int64, error) {
+	if !r.opts.countHeader {
+		return 0, ErrCountHeaderDisabled
+	}
+	if r.count < 0 {
+		return 0, r.err
+	}
+	return r.count, nil
 }
 
-var ErrNotEnoughBytes = errors.New("not enough bytes")
+// AtEnd tells whether the whole backing data slice has been sliced,
+// so that callers can tell a cleanly consumed stream from one with
+// unexpected trailing bytes.
+func (r *ByteBlockSlicer) AtEnd() bool {
+	return r.numBytesSliced == int64(len(r.data))
+}
+
+// LimitBlocks makes the slicer return io.EOF once it has sliced k more
+// blocks, even if more data follows, so that it only covers a range of
+// the stream. A negative k removes the limit.
+func (r *ByteBlockSlicer) LimitBlocks(k int64) {
+	if k < 0 {
+		k = -1
+	}
+	r.blocksLeft = k
+}
+
+// MaxBlockSize returns the length of the longest block sliced so far,
+// which is handy for sizing a buffer reused by a later pass.
+func (r *ByteBlockSlicer) MaxBlockSize() int64 {
+	return r.maxLength
+}
+
+// Clone returns a new slicer over the same backing data slice,
+// starting at the current position of r but without its error. The
+// clone and r can then be advanced independently, even in different
+// goroutines, as neither modifies the backing data slice.
+func (r *ByteBlockSlicer) Clone() *ByteBlockSlicer {
+	c := *r
+	c.err = nil
+	return &c
+}
+
+// SliceNested slices the next block and returns a new slicer over its
+// data, for blocks holding a nested stream written with the same
+// options, from its preamble to its index footer or MAC, if any. The
+// nested slicer shares memory with r but cannot reach past the block,
+// and positions in the nested stream count from the start of the block
+// data.
+func (r *ByteBlockSlicer) SliceNested() (*ByteBlockSlicer, error) {
+	data, err := r.Slice()
+	if err != nil {
+		return nil, err
+	}
+	return newSlicer(data[:len(data):len(data)], r.opts), nil
+}
+
+var (
+	ErrNotEnoughBytes        = errors.New("not enough bytes")
+	ErrInvalidOffset         = errors.New("invalid block offset")
+	ErrIndexOutOfRange       = errors.New("block index out of range")
+	ErrStrideDisabled        = errors.New("fixed stride is not enabled")
+	ErrOrderViolation        = errors.New("blocks are out of order")
+	ErrChecksumMismatch      = errors.New("block checksum mismatch")
+	ErrFrameChecksumMismatch = errors.New("block frame checksum mismatch")
+)
 
+// rawSlice slices the next n bytes out of the backing data slice. n
+// must be non-negative. The bounds check is written so that it cannot
+// overflow even if n comes from a corrupted header.
 func (r *ByteBlockSlicer) rawSlice(n int64) ([]byte, error) {
-	if r.numBytesSliced+n > int64(len(r.data)) {
+	if n > int64(len(r.data))-r.numBytesSliced {
 		return nil, ErrNotEnoughBytes
 	}
 	data := r.data[r.numBytesSliced : r.numBytesSliced+n]
@@ -2,7 +2,12 @@ package byteblock
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -116,6 +121,330 @@ func TestNewBlockAndAppend(t *testing.T) {
 	}
 }
 
+func TestWriteFunc(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, length := range []int64{10, 3, 100, 0} {
+		if err := writer.WriteFunc(length, 8, func(b []byte) error {
+			if int64(len(b)) != length {
+				t.Errorf("expected buffer of %d bytes; got %d", length, len(b))
+			}
+			for i := range b {
+				b[i] = byte(i * 7)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := errors.New("fill failed")
+	numBytes := buf.Len()
+	if err := writer.WriteFunc(5, 8, func([]byte) error { return expected }); err != expected {
+		t.Errorf("expected %v; got %v", expected, err)
+	}
+	if buf.Len() != numBytes {
+		t.Errorf("expected nothing written after failed fill; got %d bytes", buf.Len()-numBytes)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, length := range []int{10, 3, 100, 0} {
+		slice, err := slicer.Slice()
+		if err != nil || len(slice) != length {
+			t.Fatalf("expected %d bytes; got %d, %v", length, len(slice), err)
+		}
+		for i, b := range slice {
+			if b != byte(i*7) {
+				t.Errorf("byte %d: expected %d; got %d", i, byte(i*7), b)
+			}
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+}
+
+func TestWriteLoop(t *testing.T) {
+	blocks := []string{"hello", "world", "!"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	i := 0
+	if err := writer.WriteLoop(func() ([]byte, int64, bool, error) {
+		if i == len(blocks) {
+			return nil, 0, false, nil
+		}
+		i++
+		return []byte(blocks[i-1]), 8, true, nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range blocks {
+		if slice, err := slicer.Slice(); string(slice) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, slice, err)
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	expected := errors.New("producer failed")
+	if err := writer.WriteLoop(func() ([]byte, int64, bool, error) {
+		return nil, 0, true, expected
+	}); err != expected {
+		t.Errorf("expected %v; got %v", expected, err)
+	}
+
+	writer = NewByteBlockWriter(&buf, WithStrict())
+	if err := writer.WriteLoop(func() ([]byte, int64, bool, error) {
+		return nil, 0, true, nil
+	}); err != ErrInvalidLength {
+		t.Errorf("expected ErrInvalidLength; got %v", err)
+	}
+}
+
+func TestAppendStringv(t *testing.T) {
+	parts := []string{"hello", ", ", "", "wor", "ld"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	if err := writer.NewBlock(8, 12); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writer.AppendStringv(parts[:2]...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writer.AppendStringv(parts[2:]...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slice, err := NewByteBlockSlicer(buf.Bytes()).Slice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(slice) != "hello, world" {
+		t.Errorf("got %q", slice)
+	}
+
+	buf.Reset()
+	writer = NewByteBlockWriter(&buf)
+	writer.NewBlock(0, 4)
+	numBytes := buf.Len()
+	if err := writer.AppendStringv("xx", "xxx"); err != ErrWriteMoreThanRequested {
+		t.Errorf("expected ErrWriteMoreThanRequested; got %v", err)
+	}
+	if buf.Len() != numBytes {
+		t.Errorf("expected nothing written; got %d bytes", buf.Len()-numBytes)
+	}
+}
+
+func TestNewBlockAt(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.Write([]byte("hello"), 0)
+	if err := writer.NewBlockAt(0x1000, 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.Len() != 0x1000 {
+		t.Errorf("expected data to start at 0x1000; got %#x", buf.Len())
+	}
+	writer.Append([]byte("world"))
+	// Data right after the header needs no padding.
+	if err := writer.NewBlockAt(0x1005+16, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writer.Append([]byte("!"))
+
+	if got := string(buf.Bytes()[0x1000:0x1005]); got != "world" {
+		t.Errorf("expected world at 0x1000; got %q", got)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range []string{"hello", "world", "!"} {
+		if slice, err := slicer.Slice(); string(slice) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, slice, err)
+		}
+	}
+
+	if err := writer.NewBlockAt(int64(buf.Len())+15, 1); err != ErrImpossibleOffset {
+		t.Errorf("expected ErrImpossibleOffset; got %v", err)
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	src := strings.NewReader("hello world")
+	if err := writer.NewBlock(8, 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, err := writer.ReadFrom(src); n != 5 || err != nil {
+		t.Errorf("expected 5 bytes; got %d, %v", n, err)
+	}
+	if err := writer.NewBlock(8, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, err := writer.ReadFrom(src); n != 6 || err != nil {
+		t.Errorf("expected 6 bytes at EOF; got %d, %v", n, err)
+	}
+	if n, err := writer.ReadFrom(strings.NewReader("!!!!!!")); n != 4 || err != nil {
+		t.Errorf("expected 4 bytes; got %d, %v", n, err)
+	}
+	// io.Copy fills a block through ReadFrom.
+	bw, err := writer.NewBlockWriter(8, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := bw.(io.ReaderFrom); !ok {
+		t.Errorf("expected an io.ReaderFrom")
+	}
+	if n, err := io.Copy(bw, strings.NewReader("copied!")); n != 7 || err != nil {
+		t.Errorf("expected 7 bytes; got %d, %v", n, err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range []string{"hello", " world!!!!", "copied!"} {
+		slice, err := slicer.Slice()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(slice) != expected {
+			t.Errorf("expected %q; got %q", expected, slice)
+		}
+	}
+}
+
+type syncBuffer struct {
+	bytes.Buffer
+	numSyncs int
+	err      error
+}
+
+func (b *syncBuffer) Sync() error {
+	b.numSyncs++
+	return b.err
+}
+
+func TestSync(t *testing.T) {
+	var buf syncBuffer
+	writer := NewByteBlockWriter(&buf)
+	if err := writer.Sync(); err != nil || buf.numSyncs != 1 {
+		t.Errorf("expected 1 sync; got %d, %v", buf.numSyncs, err)
+	}
+	writer.NewBlock(8, 10)
+	writer.Append([]byte("hello"))
+	if err := writer.Sync(); err != ErrBlockNotFinished || buf.numSyncs != 1 {
+		t.Errorf("expected ErrBlockNotFinished without sync; got %d, %v", buf.numSyncs, err)
+	}
+	writer.Append([]byte("world"))
+	if err := writer.Sync(); err != nil || buf.numSyncs != 2 {
+		t.Errorf("expected 2 syncs; got %d, %v", buf.numSyncs, err)
+	}
+
+	buf.err = errors.New("sync failed")
+	if err := writer.Sync(); err != buf.err {
+		t.Errorf("expected %v; got %v", buf.err, err)
+	}
+	if err := writer.Write([]byte("hello"), 0); err != buf.err {
+		t.Errorf("expected %v after failed sync; got %v", buf.err, err)
+	}
+
+	var plain bytes.Buffer
+	if err := NewByteBlockWriter(&plain).Sync(); err != nil {
+		t.Errorf("expected no-op sync; got %v", err)
+	}
+}
+
+func TestNextHeaderOffset(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	if offset := writer.NextHeaderOffset(); offset != 0 {
+		t.Errorf("expected 0; got %d", offset)
+	}
+	for _, align := range []int64{0, 8, 64} {
+		offset := writer.NextHeaderOffset()
+		writer.Write([]byte("hello"), align)
+		if length := readInt64(buf.Bytes()[offset:]); length != 5 {
+			t.Errorf("align %d: expected header at %d; got length %d", align, offset, length)
+		}
+	}
+	writer.NewBlock(16, 10)
+	writer.Append([]byte("hello"))
+	offset := writer.NextHeaderOffset()
+	writer.Append([]byte("world"))
+	if offset != int64(buf.Len()) {
+		t.Errorf("expected %d with a pending block; got %d", buf.Len(), offset)
+	}
+}
+
+func TestPaddingBytes(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	var expected int64
+	for _, align := range []int64{0, 4, 8, 16, 31, 127, 1} {
+		expected += alignOffset(align, int64(buf.Len())+16)
+		if err := writer.Write([]byte("hello"), align); err != nil {
+			t.Fatalf("align %d: unexpected error: %v", align, err)
+		}
+		if padding := writer.PaddingBytes(); padding != expected {
+			t.Errorf("align %d: expected %d; got %d", align, expected, padding)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, s := range []string{"a", "bb", "ccc", "dddd", "eeeee"} {
+		writer.WriteString(s, 4)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	slicer.Slice()
+	slicer.Slice()
+
+	clones := []*ByteBlockSlicer{slicer.Clone(), slicer.Clone()}
+	if slice, _ := clones[0].Slice(); string(slice) != "ccc" {
+		t.Errorf("clone 0: expected ccc; got %q", slice)
+	}
+	for _, expected := range []string{"ccc", "dddd", "eeeee"} {
+		if slice, _ := clones[1].Slice(); string(slice) != expected {
+			t.Errorf("clone 1: expected %q; got %q", expected, slice)
+		}
+	}
+	if _, err := clones[1].Slice(); err != io.EOF {
+		t.Errorf("clone 1: expected io.EOF; got %v", err)
+	}
+	for _, expected := range []string{"dddd", "eeeee"} {
+		if slice, _ := clones[0].Slice(); string(slice) != expected {
+			t.Errorf("clone 0: expected %q; got %q", expected, slice)
+		}
+	}
+	if slice, _ := slicer.Slice(); string(slice) != "ccc" {
+		t.Errorf("original: expected ccc; got %q", slice)
+	}
+}
+
+func TestAtEnd(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.Write([]byte("hello"), 8)
+	writer.Write([]byte("world"), 8)
+
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for i := 0; i < 2; i++ {
+		if slicer.AtEnd() {
+			t.Errorf("block %d: unexpected end", i)
+		}
+		slicer.Slice()
+	}
+	if !slicer.AtEnd() {
+		t.Errorf("expected end after all blocks")
+	}
+
+	slicer = NewByteBlockSlicer(append(buf.Bytes(), 0, 0, 0))
+	slicer.Slice()
+	slicer.Slice()
+	if slicer.AtEnd() {
+		t.Errorf("expected trailing bytes")
+	}
+}
+
 func TestNotEnoughBytes(t *testing.T) {
 	var buf bytes.Buffer
 	NewByteBlockWriter(&buf).Write([]byte("hello"), 7)
@@ -132,3 +461,889 @@ func TestNotEnoughBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestStrict(t *testing.T) {
+	for _, i := range []struct {
+		Align, Length int64
+		Err           error
+	}{
+		{1, 1, nil}, {8, 5, nil},
+		{1, 0, ErrInvalidLength}, {8, -1, ErrInvalidLength},
+		{0, 1, ErrInvalidAlign}, {-1, 1, ErrInvalidAlign},
+	} {
+		var buf bytes.Buffer
+		if err := NewByteBlockWriter(&buf, WithStrict()).NewBlock(i.Align, i.Length); err != i.Err {
+			t.Errorf("case %+v: strict got %v", i, err)
+		}
+		if i.Length < 0 {
+			continue
+		}
+		buf.Reset()
+		if err := NewByteBlockWriter(&buf).NewBlock(i.Align, i.Length); err != nil {
+			t.Errorf("case %+v: non-strict got %v", i, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewByteBlockWriter(&buf, WithStrict()).Write(nil, 8); err != ErrInvalidLength {
+		t.Errorf("expected ErrInvalidLength from Write; got %v", err)
+	}
+}
+
+func TestAbsoluteOffset(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithAbsoluteOffset())
+	data := []struct {
+		Data  string
+		Align int64
+	}{
+		{"hello", 0}, {"world", 16}, {"hello", 7}, {"world", 64},
+	}
+	var dataPos []int64
+	for _, d := range data {
+		writer.WriteString(d.Data, d.Align)
+		dataPos = append(dataPos, int64(buf.Len()-len(d.Data)))
+	}
+
+	headerPos := int64(0)
+	for i, d := range data {
+		if pos := readInt64(buf.Bytes()[headerPos+8:]); pos != dataPos[i] {
+			t.Errorf("record %d: expected data position %d; got %d", i, dataPos[i], pos)
+		}
+		headerPos = dataPos[i] + int64(len(d.Data))
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithAbsoluteOffset())
+	for _, d := range data {
+		slice, err := slicer.Slice()
+		if err != nil {
+			t.Fatalf("record %+v: unexpected error: %v", d, err)
+		}
+		if string(slice) != d.Data {
+			t.Errorf("record %+v: got %q", d, slice)
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	// A data position pointing back into the header is invalid.
+	b := append([]byte(nil), buf.Bytes()...)
+	fillInt64(8, b[8:])
+	if _, err := NewByteBlockSlicer(b, WithAbsoluteOffset()).Slice(); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("expected ErrInvalidOffset; got %v", err)
+	}
+}
+
+func TestCorruptedHeader(t *testing.T) {
+	const max = math.MaxInt64
+	for _, i := range []struct {
+		Length, Offset int64
+		Err            error
+	}{
+		{max, 0, ErrNotEnoughBytes},
+		{0, max, ErrNotEnoughBytes},
+		{max, max, ErrNotEnoughBytes},
+		{max - 16, max - 16, ErrNotEnoughBytes},
+		{4, max - 15, ErrNotEnoughBytes},
+		{-1, 0, ErrInvalidLength},
+		{math.MinInt64, 0, ErrInvalidLength},
+		{0, -1, ErrInvalidOffset},
+		{4, math.MinInt64, ErrInvalidOffset},
+	} {
+		data := make([]byte, 32)
+		fillInt64(i.Length, data)
+		fillInt64(i.Offset, data[8:])
+		slicer := NewByteBlockSlicer(data)
+		if _, err := slicer.Slice(); !errors.Is(err, i.Err) {
+			t.Errorf("case %+v: got %v", i, err)
+		}
+		if _, err := slicer.Slice(); !errors.Is(err, i.Err) {
+			t.Errorf("case %+v: got %v (in error state)", i, err)
+		}
+		if _, err := ReadBlockRange(bytes.NewReader(data), 0, 0, 0); !errors.Is(err, i.Err) {
+			t.Errorf("case %+v: ReadBlockRange got %v", i, err)
+		}
+	}
+}
+
+func TestHeaderLimits(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.Write([]byte("short"), 8)
+	writer.Write(make([]byte, 100), 8)
+	writer.Write([]byte("aligned"), 256)
+	data := buf.Bytes()
+
+	for _, i := range []struct {
+		MaxLength, MaxPadding int64
+		Blocks                int
+		Err                   error
+		Offset                int64
+	}{
+		{0, 0, 3, nil, 0},
+		{100, 200, 3, nil, 0},
+		{99, 0, 1, ErrLengthLimit, 21},
+		{0, 99, 2, ErrPaddingLimit, 140},
+	} {
+		slicer := NewByteBlockSlicer(data, WithHeaderLimits(i.MaxLength, i.MaxPadding))
+		blocks := 0
+		var err error
+		for ; err == nil; blocks++ {
+			_, err = slicer.Slice()
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		if blocks-1 != i.Blocks || !errors.Is(err, i.Err) {
+			t.Errorf("case %+v: got %d blocks, %v", i, blocks-1, err)
+		}
+		var headerErr *HeaderError
+		if i.Err != nil && (!errors.As(err, &headerErr) || headerErr.Offset != i.Offset) {
+			t.Errorf("case %+v: expected a *HeaderError at offset %d; got %v", i, i.Offset, err)
+		}
+	}
+
+	reader := NewByteBlockReader(bytes.NewReader(data), WithHeaderLimits(99, 0))
+	reader.Read()
+	if _, err := reader.Read(); !errors.Is(err, ErrLengthLimit) {
+		t.Errorf("expected ErrLengthLimit; got %v", err)
+	}
+
+	// Decompressed data is limited too, so that a small block cannot
+	// inflate without bounds.
+	opts := []Option{WithCodec(CodecFlate), WithHeaderLimits(4096, 0)}
+	buf.Reset()
+	writer = NewByteBlockWriter(&buf, opts...)
+	writer.Write(make([]byte, 4096), 8)
+	writer.Write(make([]byte, 4097), 8)
+	writer.Close()
+	data = buf.Bytes()
+	slicer := NewByteBlockSlicer(data, opts...)
+	streaming := NewByteBlockReader(bytes.NewReader(data), opts...)
+	reader = NewByteBlockReader(bytes.NewReader(data), opts...)
+	for _, expected := range []error{nil, ErrLengthLimit} {
+		if _, err := slicer.Slice(); err != expected {
+			t.Errorf("expected %v; got %v", expected, err)
+		}
+		if _, err := reader.Read(); err != expected {
+			t.Errorf("expected %v; got %v", expected, err)
+		}
+		r, err := streaming.NextReader()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := io.ReadAll(r); err != expected {
+			t.Errorf("expected %v; got %v", expected, err)
+		}
+	}
+}
+
+func TestCountHeader(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithCountHeader()},
+		{WithCountHeader(), WithMagicHeader(), WithChecksums()},
+	} {
+		for _, numBlocks := range []int{3, 2, 4} {
+			var buf bytes.Buffer
+			writer := NewByteBlockWriter(&buf, opts...)
+			if err := writer.WriteCountHeader(3); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			layout := NewLayout(opts...)
+			for i := 0; i < numBlocks; i++ {
+				offset := writer.NextHeaderOffset()
+				if info, _ := layout.Add(8, 5); info.Offset != offset {
+					t.Errorf("expected header offset %d; got %d", offset, info.Offset)
+				}
+				writer.Write([]byte("hello"), 8)
+			}
+			err := writer.Close()
+			if numBlocks == 3 && err != nil {
+				t.Errorf("%d blocks: unexpected error: %v", numBlocks, err)
+			} else if numBlocks != 3 && err != ErrCountMismatch {
+				t.Errorf("%d blocks: expected ErrCountMismatch; got %v", numBlocks, err)
+			}
+			data := buf.Bytes()
+			if size := layout.Size(); size != int64(len(data)) {
+				t.Errorf("expected size %d; got %d", len(data), size)
+			}
+
+			slicer := NewByteBlockSlicer(data, opts...)
+			reader := NewByteBlockReader(bytes.NewReader(data), opts...)
+			if n, err := reader.ReadCount(); n != 3 || err != nil {
+				t.Errorf("%d blocks: expected count 3; got %d, %v", numBlocks, n, err)
+			}
+			readerAt := NewByteBlockReaderAt(bytes.NewReader(data), int64(len(data)), opts...)
+			for i := 0; i < numBlocks; i++ {
+				if slice, err := slicer.Slice(); string(slice) != "hello" || err != nil {
+					t.Errorf("%d blocks: block %d: got %q, %v", numBlocks, i, slice, err)
+				}
+				if block, err := reader.Read(); string(block) != "hello" || err != nil {
+					t.Errorf("%d blocks: block %d: got %q, %v", numBlocks, i, block, err)
+				}
+				if block, err := readerAt.ReadBlock(int64(i)); string(block) != "hello" || err != nil {
+					t.Errorf("%d blocks: block %d: got %q, %v", numBlocks, i, block, err)
+				}
+			}
+			// The count can be read at any time.
+			if n, err := slicer.ReadCount(); n != 3 || err != nil {
+				t.Errorf("%d blocks: expected count 3; got %d, %v", numBlocks, n, err)
+			}
+			if n, err := reader.ReadCount(); n != 3 || err != nil {
+				t.Errorf("%d blocks: expected count 3; got %d, %v", numBlocks, n, err)
+			}
+			if n, err := Count(data, opts...); n != int64(numBlocks) || err != nil {
+				t.Errorf("expected %d blocks; got %d, %v", numBlocks, n, err)
+			}
+			if n, err := Validate(bytes.NewReader(data), opts...); n != int64(numBlocks) || err != nil {
+				t.Errorf("expected %d blocks; got %d, %v", numBlocks, n, err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithCountHeader())
+	if err := writer.Write([]byte("hello"), 8); err != ErrNoCountHeader {
+		t.Errorf("expected ErrNoCountHeader; got %v", err)
+	}
+	writer = NewByteBlockWriter(&buf, WithCountHeader())
+	writer.WriteCountHeader(1)
+	if err := writer.WriteCountHeader(1); err != ErrCountHeaderNotFirst {
+		t.Errorf("expected ErrCountHeaderNotFirst; got %v", err)
+	}
+	if err := NewByteBlockWriter(&buf).WriteCountHeader(1); err != ErrCountHeaderDisabled {
+		t.Errorf("expected ErrCountHeaderDisabled; got %v", err)
+	}
+	if _, err := NewByteBlockSlicer(nil).ReadCount(); err != ErrCountHeaderDisabled {
+		t.Errorf("expected ErrCountHeaderDisabled; got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.NewBlock(0, 5)
+	writer.Append([]byte("hell"))
+	if err := writer.Close(); err != ErrBlockNotFinished {
+		t.Errorf("expected ErrBlockNotFinished; got %v", err)
+	}
+	writer.Append([]byte("o"))
+	if err := writer.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := writer.Write([]byte("world"), 0); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed; got %v", err)
+	}
+}
+
+func TestAutoBlock(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithAutoBlock(8))
+	writer.Append([]byte("hello"))
+	writer.AppendString("world")
+	writer.NewBlock(16, 6)
+	writer.Append([]byte("foo"))
+	writer.AppendString("bar")
+	writer.Append([]byte("!"))
+	if err := writer.Write([]byte("baz"), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writer.Write(nil, 0)
+	if err := writer.Append([]byte("end")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range []string{"hello", "world", "foobar", "!", "baz", "", "end"} {
+		slice, err := slicer.Slice()
+		if string(slice) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, slice, err)
+		}
+		if expected == "hello" || expected == "!" {
+			if start := slicer.numBytesSliced - int64(len(slice)); start%8 != 0 {
+				t.Errorf("%q: misaligned data starting at %d", expected, start)
+			}
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	writer = NewByteBlockWriter(&buf)
+	if err := writer.Append([]byte("x")); err != ErrWriteMoreThanRequested {
+		t.Errorf("expected ErrWriteMoreThanRequested without auto block; got %v", err)
+	}
+}
+
+func TestAlignInHeader(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithAlignInHeader())
+	data := []struct {
+		Data  string
+		Align int64
+	}{
+		{"hello", 0}, {"world", 16}, {"hello", 7}, {"world", 64},
+	}
+	for _, d := range data {
+		writer.WriteString(d.Data, d.Align)
+	}
+	writer.NewBlockAt(0x1000, 1)
+	writer.Append([]byte("!"))
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithAlignInHeader())
+	for _, d := range data {
+		slice, err := slicer.Slice()
+		if string(slice) != d.Data || err != nil {
+			t.Errorf("record %+v: got %q, %v", d, slice, err)
+		}
+		if start := slicer.numBytesSliced - int64(len(slice)); d.Align > 1 && start%d.Align != 0 {
+			t.Errorf("record %+v: misaligned data starting at %d", d, start)
+		}
+	}
+	if slice, err := slicer.Slice(); string(slice) != "!" || err != nil || slicer.numBytesSliced != 0x1001 {
+		t.Errorf("expected ! at 0x1000; got %q, %v ending at %#x", slice, err, slicer.numBytesSliced)
+	}
+
+	// The same block written at different positions has the same
+	// header but different padding, which the slicer recomputes.
+	var header []byte
+	for prefix := 0; prefix < 16; prefix++ {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, WithAlignInHeader())
+		writer.Write(make([]byte, prefix), 1)
+		headerPos := buf.Len()
+		writer.WriteString("hello", 16)
+		if header == nil {
+			header = append(header, buf.Bytes()[headerPos:headerPos+16]...)
+		} else if !bytes.Equal(buf.Bytes()[headerPos:headerPos+16], header) {
+			t.Errorf("prefix %d: header %v differs from %v", prefix, buf.Bytes()[headerPos:headerPos+16], header)
+		}
+		if padding := alignOffset(16, int64(headerPos+16)); buf.Len() != headerPos+16+int(padding)+5 {
+			t.Errorf("prefix %d: expected %d bytes of padding", prefix, padding)
+		}
+		slicer := NewByteBlockSlicer(buf.Bytes(), WithAlignInHeader())
+		slicer.Slice()
+		if slice, err := slicer.Slice(); string(slice) != "hello" || err != nil {
+			t.Errorf("prefix %d: got %q, %v", prefix, slice, err)
+		}
+	}
+}
+
+func TestInitialCapacity(t *testing.T) {
+	var buf bytes.Buffer
+	NewByteBlockWriter(&buf, WithInitialCapacity(1000))
+	if buf.Cap() < 1000 {
+		t.Errorf("expected capacity of at least 1000; got %d", buf.Cap())
+	}
+	// Writers without Grow ignore the hint.
+	var sb strings.Builder
+	NewByteBlockWriter(struct{ io.Writer }{&sb}, WithInitialCapacity(1000))
+	if sb.Cap() != 0 {
+		t.Errorf("expected capacity 0; got %d", sb.Cap())
+	}
+}
+
+func benchmarkInitialCapacity(b *testing.B, opts ...Option) {
+	data := make([]byte, 1000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for j := 0; j < 100; j++ {
+			writer.Write(data, 64)
+		}
+	}
+}
+
+func BenchmarkWithoutInitialCapacity(b *testing.B) {
+	benchmarkInitialCapacity(b)
+}
+
+func BenchmarkWithInitialCapacity(b *testing.B) {
+	// 100 blocks of 1000 bytes, each with a 16-byte header and at most
+	// 63 bytes of padding.
+	benchmarkInitialCapacity(b, WithInitialCapacity(100*(16+63+1000)))
+}
+
+func TestFixedStride(t *testing.T) {
+	const stride = 64
+	blocks := []string{"hello", "", "world", strings.Repeat("x", 40), "!"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithFixedStride(stride))
+	for i, b := range blocks {
+		if offset := writer.NextHeaderOffset(); offset != int64(i*stride) {
+			t.Errorf("block %d: expected header at %d; got %d", i, i*stride, offset)
+		}
+		if i == 3 {
+			writer.NewBlock(8, int64(len(b)))
+			writer.AppendString(b[:20])
+			if offset := writer.NextHeaderOffset(); offset != int64((i+1)*stride) {
+				t.Errorf("block %d: expected next header at %d; got %d", i, (i+1)*stride, offset)
+			}
+			writer.AppendString(b[20:])
+		} else if err := writer.WriteString(b, 8); err != nil {
+			t.Fatalf("block %d: unexpected error: %v", i, err)
+		}
+		if buf.Len() != (i+1)*stride {
+			t.Errorf("block %d: expected %d bytes written; got %d", i, (i+1)*stride, buf.Len())
+		}
+	}
+	if err := writer.Write(make([]byte, stride-15), 8); err != ErrBlockTooLarge {
+		t.Errorf("expected ErrBlockTooLarge; got %v", err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithFixedStride(stride))
+	for _, i := range []int64{3, 0, 4, 1, 2} {
+		if data, err := slicer.SliceStride(i); string(data) != blocks[i] || err != nil {
+			t.Errorf("block %d: expected %q; got %q, %v", i, blocks[i], data, err)
+		}
+	}
+	slicer.SliceStride(1)
+	for _, expected := range blocks[2:] {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	for _, i := range []int64{-1, 5} {
+		if _, err := slicer.SliceStride(i); err != ErrIndexOutOfRange {
+			t.Errorf("block %d: expected ErrIndexOutOfRange; got %v", i, err)
+		}
+	}
+	if _, err := NewByteBlockSlicer(buf.Bytes()).SliceStride(0); err != ErrStrideDisabled {
+		t.Errorf("expected ErrStrideDisabled; got %v", err)
+	}
+
+	reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), WithFixedStride(stride))
+	reader.Read()
+	if n, err := reader.Drain(); n != 4 || err != nil {
+		t.Errorf("expected 4 blocks drained; got %d, %v", n, err)
+	}
+}
+
+func TestMaxPaddingRatio(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithMaxPaddingRatio(1))
+	// 16 bytes of padding after the 16-byte header.
+	if err := writer.Write(make([]byte, 16), 32); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Empty blocks are exempt.
+	if err := writer.NewBlock(4096, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := writer.Write(make([]byte, 4), 4096); err != ErrExcessivePadding {
+		t.Errorf("expected ErrExcessivePadding; got %v", err)
+	}
+
+	if err := NewByteBlockWriter(&buf).Write(make([]byte, 4), 4096); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWriteSplit(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	if err := writer.WriteSplit(data, 3, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	var joined []byte
+	for i, expected := range []int{333, 333, 334} {
+		block, err := slicer.Slice()
+		if err != nil {
+			t.Fatalf("block %d: unexpected error: %v", i, err)
+		}
+		if len(block) != expected {
+			t.Errorf("block %d: expected length %d; got %d", i, expected, len(block))
+		}
+		joined = append(joined, block...)
+	}
+	if !slicer.AtEnd() {
+		t.Errorf("expected no more blocks")
+	}
+	if !bytes.Equal(joined, data) {
+		t.Errorf("concatenated blocks differ from the original")
+	}
+
+	if err := NewByteBlockWriter(&buf).WriteSplit(data, 0, 8); err != ErrInvalidSplit {
+		t.Errorf("expected ErrInvalidSplit; got %v", err)
+	}
+}
+
+func TestOrderCheck(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, length := range []int{0, 1, 5, 5, 10} {
+		writer.Write(make([]byte, length), 8)
+	}
+	increasing := WithOrderCheck(func(prev, cur int64) bool { return prev < cur })
+	slicer := NewByteBlockSlicer(buf.Bytes(), increasing)
+	for i := 0; i < 3; i++ {
+		if _, err := slicer.Slice(); err != nil {
+			t.Errorf("block %d: unexpected error: %v", i, err)
+		}
+	}
+	if _, err := slicer.Slice(); err != ErrOrderViolation {
+		t.Errorf("expected ErrOrderViolation; got %v", err)
+	}
+	if _, err := slicer.Slice(); err != ErrOrderViolation {
+		t.Errorf("expected sticky ErrOrderViolation; got %v", err)
+	}
+
+	nondecreasing := WithOrderCheck(func(prev, cur int64) bool { return prev <= cur })
+	slicer = NewByteBlockSlicer(buf.Bytes(), nondecreasing)
+	for i := 0; i < 5; i++ {
+		if _, err := slicer.Slice(); err != nil {
+			t.Errorf("block %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestMaxBlockSize(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithInlineNames())
+	for _, length := range []int{3, 100, 0, 42} {
+		writer.Write(make([]byte, length), 8)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithInlineNames())
+	if n := slicer.MaxBlockSize(); n != 0 {
+		t.Errorf("expected 0 before slicing; got %d", n)
+	}
+	slicer.Slice()
+	if n := slicer.MaxBlockSize(); n != 3 {
+		t.Errorf("expected 3; got %d", n)
+	}
+	for !slicer.AtEnd() {
+		slicer.Slice()
+	}
+	if n := slicer.MaxBlockSize(); n != 100 {
+		t.Errorf("expected 100; got %d", n)
+	}
+}
+
+func TestSliceNested(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithAlignInHeader()},
+		{WithCountHeader()},
+		{WithMagicHeader(), WithChecksums()},
+		{WithIndexFooter()},
+		{WithStreamMAC([]byte("secret key"))},
+	} {
+		o := newOptions(opts)
+		var inner bytes.Buffer
+		writer := NewByteBlockWriter(&inner, opts...)
+		if o.countHeader {
+			writer.WriteCountHeader(3)
+		}
+		for _, b := range []string{"a", "bc", "def"} {
+			writer.WriteString(b, 8)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var buf bytes.Buffer
+		writer = NewByteBlockWriter(&buf, opts...)
+		if o.countHeader {
+			writer.WriteCountHeader(3)
+		}
+		writer.WriteString("before", 8)
+		// The nested stream is aligned like its own blocks so that their
+		// alignment holds in the outer stream too.
+		writer.Write(inner.Bytes(), 8)
+		writer.WriteString("after", 8)
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		slicer.Slice()
+		nested, err := slicer.SliceNested()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, expected := range []string{"a", "bc", "def"} {
+			if data, err := nested.Slice(); string(data) != expected || err != nil {
+				t.Errorf("%v: expected %q; got %q, %v", opts, expected, data, err)
+			}
+		}
+		if _, err := nested.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+		if data, err := slicer.Slice(); string(data) != "after" || err != nil {
+			t.Errorf("expected %q; got %q, %v", "after", data, err)
+		}
+		if _, err := slicer.SliceNested(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+	}
+}
+
+func TestHeaderFieldOrder(t *testing.T) {
+	blocks := []string{"hello", "", "world"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithHeaderFieldOrder(true))
+	for _, b := range blocks {
+		writer.WriteString(b, 32)
+	}
+	// The first block has 16 bytes of padding and 5 bytes of data.
+	if offset, length := readInt64(buf.Bytes()), readInt64(buf.Bytes()[8:]); offset != 16 || length != 5 {
+		t.Errorf("expected offset 16 and length 5; got %d and %d", offset, length)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithHeaderFieldOrder(true))
+	for _, expected := range blocks {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	if !slicer.AtEnd() {
+		t.Errorf("expected the whole stream to be sliced")
+	}
+
+	slicer = NewByteBlockSlicer(buf.Bytes(), WithHeaderFieldOrder(false))
+	if data, err := slicer.Slice(); string(data) == "hello" && err == nil {
+		t.Errorf("expected length-first order to misread the stream")
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	blocks := []string{"hello", "", "world"}
+	for _, opts := range [][]Option{
+		{WithChecksums()},
+		{WithChecksums(), WithInlineNames(), WithFrameChecksum(), WithBackLink(), WithFixedStride(96)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		writer.WriteString(blocks[0], 8)
+		writer.NewBlock(16, 0)
+		writer.NewBlock(32, 5)
+		writer.ReadFrom(strings.NewReader(blocks[2]))
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		for _, expected := range blocks {
+			if data, err := slicer.Slice(); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+		}
+		if !slicer.AtEnd() {
+			t.Errorf("expected the whole stream to be sliced")
+		}
+		for _, drain := range []bool{false, true} {
+			reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+			if drain {
+				if n, err := reader.Drain(); n != len(blocks) || err != nil {
+					t.Errorf("expected %d blocks; got %d, %v", len(blocks), n, err)
+				}
+				continue
+			}
+			for _, expected := range blocks {
+				if data, err := reader.Read(); string(data) != expected || err != nil {
+					t.Errorf("expected %q; got %q, %v", expected, data, err)
+				}
+			}
+		}
+
+		// Flip the last byte of the data of the first block.
+		data := append([]byte(nil), buf.Bytes()...)
+		data[bytes.Index(data, []byte("hello"))+4] ^= 1
+		if _, err := NewByteBlockSlicer(data, opts...).Slice(); err != ErrChecksumMismatch {
+			t.Errorf("expected ErrChecksumMismatch; got %v", err)
+		}
+		if _, err := NewByteBlockReader(bytes.NewReader(data), opts...).Read(); err != ErrChecksumMismatch {
+			t.Errorf("expected ErrChecksumMismatch; got %v", err)
+		}
+		if _, err := NewByteBlockReader(bytes.NewReader(data), opts...).Drain(); err != ErrChecksumMismatch {
+			t.Errorf("expected ErrChecksumMismatch; got %v", err)
+		}
+	}
+}
+
+func TestFrameChecksum(t *testing.T) {
+	blocks := []string{"hello", "", "world"}
+	for _, opts := range [][]Option{
+		{WithFrameChecksum()},
+		{WithFrameChecksum(), WithBackLink(), WithFixedStride(64)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		writer.WriteString(blocks[0], 8)
+		writer.NewBlock(16, 0)
+		writer.NewBlock(32, 5)
+		writer.ReadFrom(strings.NewReader(blocks[2]))
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		for _, expected := range blocks {
+			if data, err := slicer.Slice(); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+		}
+		if !slicer.AtEnd() {
+			t.Errorf("expected the whole stream to be sliced")
+		}
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		if n, err := reader.Drain(); n != len(blocks) || err != nil {
+			t.Errorf("expected %d blocks; got %d, %v", len(blocks), n, err)
+		}
+
+		// Flip the lowest bit of the length of the first block, then a
+		// bit of its data.
+		for _, pos := range []int{0, 16} {
+			data := append([]byte(nil), buf.Bytes()...)
+			data[pos] ^= 1
+			if _, err := NewByteBlockSlicer(data, opts...).Slice(); err != ErrFrameChecksumMismatch {
+				t.Errorf("flipped byte %d: expected ErrFrameChecksumMismatch; got %v", pos, err)
+			}
+			reader := NewByteBlockReader(bytes.NewReader(data), opts...)
+			if _, err := reader.Read(); err != ErrFrameChecksumMismatch {
+				t.Errorf("flipped byte %d: expected ErrFrameChecksumMismatch; got %v", pos, err)
+			}
+		}
+	}
+}
+
+func TestBaseOffset(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithBaseOffset(24))
+	for _, align := range []int64{0, 1, 2, 4, 8, 3, 12} {
+		if err := writer.Write([]byte("hello"), align); err != nil {
+			t.Errorf("align %d: unexpected error: %v", align, err)
+		}
+	}
+	if err := writer.Write([]byte("hello"), 16); err != ErrAlignmentUnsatisfiable {
+		t.Errorf("expected ErrAlignmentUnsatisfiable; got %v", err)
+	}
+
+	writer = NewByteBlockWriter(&buf, WithBaseOffset(4096))
+	if err := writer.Write([]byte("hello"), 4096); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLimitBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	blocks := []string{"a", "b", "", "c", "d"}
+	for _, b := range blocks {
+		writer.WriteString(b, 8)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	slicer.Slice()
+	slicer.LimitBlocks(3)
+	for _, expected := range blocks[1:4] {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+	}
+	if slicer.AtEnd() {
+		t.Errorf("expected data to remain")
+	}
+	slicer.LimitBlocks(-1)
+	if data, err := slicer.Slice(); string(data) != "d" || err != nil {
+		t.Errorf("expected %q; got %q, %v", "d", data, err)
+	}
+}
+
+func TestAppendFrom(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.NewBlock(8, 11)
+	src := strings.NewReader("hello world and more")
+	if err := writer.AppendFrom(src, 5); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := writer.AppendFrom(src, 6); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	writer.NewBlock(8, 10)
+	if err := writer.AppendFrom(src, 10); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if err := writer.AppendFrom(strings.NewReader("12345678"), 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := writer.AppendFrom(strings.NewReader("12345678"), 1); err != ErrWriteMoreThanRequested {
+		t.Errorf("expected ErrWriteMoreThanRequested; got %v", err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range []string{"hello world", " and more1"} {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+}
+
+func TestNewBlockWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	bw, err := writer.NewBlockWriter(8, 11)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, err := fmt.Fprintf(bw, "hello %s", "world"); n != 11 || err != nil {
+		t.Errorf("unexpected result: %d, %v", n, err)
+	}
+	bw, _ = writer.NewBlockWriter(8, 4)
+	if n, err := io.Copy(bw, strings.NewReader("abcd")); n != 4 || err != nil {
+		t.Errorf("unexpected result: %d, %v", n, err)
+	}
+	bw, _ = writer.NewBlockWriter(8, 2)
+	if _, err := bw.Write([]byte("xyz")); err != ErrWriteMoreThanRequested {
+		t.Errorf("expected ErrWriteMoreThanRequested; got %v", err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range []string{"hello world", "abcd"} {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+}
+
+func TestPaddingAllocs(t *testing.T) {
+	writer := NewByteBlockWriter(io.Discard, WithFixedStride(4<<20))
+	data := []byte("data")
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := writer.Write(data, 2<<20); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations; got %v per block", allocs)
+	}
+}
+
+func TestSliceString(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	// AllocsPerRun also makes a warm-up run.
+	writer.WriteString("hello", 8)
+	writer.WriteString("hello", 8)
+	writer.WriteString("", 8)
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	var s string
+	allocs := testing.AllocsPerRun(1, func() {
+		s, _ = slicer.SliceString()
+	})
+	if s != "hello" {
+		t.Errorf("expected %q; got %q", "hello", s)
+	}
+	if allocs > 0 && zeroCopyStrings {
+		t.Errorf("expected no allocations; got %v", allocs)
+	}
+	if s, err := slicer.SliceString(); s != "" || err != nil {
+		t.Errorf("expected empty string; got %q, %v", s, err)
+	}
+	if _, err := slicer.SliceString(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+}
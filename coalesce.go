@@ -0,0 +1,122 @@
+package byteblock
+
+import "io"
+
+// Coalesce copies the blocks in src to dst with the given alignment,
+// merging each run of two or more consecutive blocks shorter than
+// minSize into a single block of KindCoalesced, which records the
+// lengths of the merged blocks so that ByteBlockSlicer.SliceCoalesced
+// can split it again. Other blocks are copied as they are. Only block
+// data is kept: inline names and other header metadata are dropped.
+//
+// src is sliced with opts, and dst is written with opts plus
+// WithBlockKinds, which is therefore needed to slice dst. The writer
+// of dst is closed at the end, which writes the index footer of
+// WithIndexFooter, for example.
+func Coalesce(dst io.Writer, src []byte, minSize int64, align int64, opts ...Option) error {
+	slicer := NewByteBlockSlicer(src, opts...)
+	writer := NewByteBlockWriter(dst, append(opts[:len(opts):len(opts)], WithBlockKinds())...)
+	var run [][]byte
+	flush := func() error {
+		var err error
+		switch len(run) {
+		case 0:
+		case 1:
+			err = writer.Write(run[0], align)
+		default:
+			err = writer.writeCoalesced(run, align)
+		}
+		run = run[:0]
+		return err
+	}
+	for {
+		block, err := slicer.Slice()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if int64(len(block)) < minSize {
+			run = append(run, block)
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		if err := writer.Write(block, align); err != nil {
+			return err
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// writeCoalesced writes blocks as a single block of KindCoalesced:
+// the number of blocks and their lengths as int64s, followed by their
+// data.
+func (w *ByteBlockWriter) writeCoalesced(blocks [][]byte, align int64) error {
+	length := 8 + 8*int64(len(blocks))
+	for _, b := range blocks {
+		length += int64(len(b))
+	}
+	if err := w.newBlock(align, length, blockMeta{flags: uint64(KindCoalesced) << flagsKindShift}); err != nil {
+		return err
+	}
+	w.fillStub(int64(len(blocks)))
+	if err := w.append(w.stub[:]); err != nil {
+		return err
+	}
+	for _, b := range blocks {
+		w.fillStub(int64(len(b)))
+		if err := w.append(w.stub[:]); err != nil {
+			return err
+		}
+	}
+	for _, b := range blocks {
+		if err := w.append(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SliceCoalesced slices the next block and returns the blocks it
+// holds: the merged blocks for a block of KindCoalesced written by
+// Coalesce, or the block itself otherwise. The returned slices share
+// memory with the backing data. The slicer must have been created with
+// WithBlockKinds; otherwise ErrBlockKindsDisabled is returned.
+// ErrInvalidLength is returned if the lengths recorded in a coalesced
+// block do not match its data; the block is still consumed.
+func (r *ByteBlockSlicer) SliceCoalesced() ([][]byte, error) {
+	data, kind, err := r.SliceKind()
+	if err != nil {
+		return nil, err
+	}
+	if kind != KindCoalesced {
+		return [][]byte{data}, nil
+	}
+	if len(data) < 8 {
+		return nil, ErrInvalidLength
+	}
+	n := readInt64(data)
+	data = data[8:]
+	if n < 0 || n > int64(len(data))/8 {
+		return nil, ErrInvalidLength
+	}
+	table, data := data[:8*n], data[8*n:]
+	blocks := make([][]byte, n)
+	for i := range blocks {
+		length := readInt64(table[8*i:])
+		if length < 0 || length > int64(len(data)) {
+			return nil, ErrInvalidLength
+		}
+		blocks[i], data = data[:length:length], data[length:]
+	}
+	if len(data) != 0 {
+		return nil, ErrInvalidLength
+	}
+	return blocks, nil
+}
@@ -0,0 +1,83 @@
+package byteblock
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCoalesce(t *testing.T) {
+	blocks := []string{"a", "bc", "", "def", "g", "a long block of many bytes", "h", "ij", "another long block"}
+	var src bytes.Buffer
+	writer := NewByteBlockWriter(&src, WithInlineNames())
+	for _, b := range blocks {
+		writer.WriteString(b, 16)
+	}
+
+	var dst bytes.Buffer
+	if err := Coalesce(&dst, src.Bytes(), 8, 8, WithInlineNames()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.Len() >= src.Len() {
+		t.Errorf("expected fewer bytes; got %d from %d", dst.Len(), src.Len())
+	}
+
+	slicer := NewByteBlockSlicer(dst.Bytes(), WithInlineNames(), WithBlockKinds())
+	var groups [][]string
+	for !slicer.AtEnd() {
+		split, err := slicer.SliceCoalesced()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var group []string
+		for _, b := range split {
+			group = append(group, string(b))
+		}
+		groups = append(groups, group)
+	}
+	expected := [][]string{
+		{"a", "bc", "", "def", "g"},
+		{"a long block of many bytes"},
+		{"h", "ij"},
+		{"another long block"},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected %q; got %q", expected, groups)
+	}
+
+	// A corrupted length table.
+	data := append([]byte(nil), dst.Bytes()...)
+	m, _ := Manifest(data, WithInlineNames(), WithBlockKinds())
+	fillInt64(100, data[m.Blocks[0].DataOffset+8:])
+	slicer = NewByteBlockSlicer(data, WithInlineNames(), WithBlockKinds())
+	if _, err := slicer.SliceCoalesced(); err != ErrInvalidLength {
+		t.Errorf("expected ErrInvalidLength; got %v", err)
+	}
+	if split, err := slicer.SliceCoalesced(); err != nil || len(split) != 1 {
+		t.Errorf("expected the next block; got %q, %v", split, err)
+	}
+
+	// The writer of dst is closed, so that footers are written.
+	src.Reset()
+	writer = NewByteBlockWriter(&src, WithIndexFooter())
+	for _, b := range blocks {
+		writer.WriteString(b, 16)
+	}
+	writer.Close()
+	dst.Reset()
+	if err := Coalesce(&dst, src.Bytes(), 8, 8, WithIndexFooter()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slicer = NewByteBlockSlicer(dst.Bytes(), WithIndexFooter(), WithBlockKinds())
+	n := 0
+	for !slicer.AtEnd() {
+		split, err := slicer.SliceCoalesced()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n += len(split)
+	}
+	if n != len(blocks) {
+		t.Errorf("expected %d blocks; got %d", len(blocks), n)
+	}
+}
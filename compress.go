@@ -0,0 +1,256 @@
+package byteblock
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+)
+
+// estimateSampleSize is the number of bytes EstimateCompressible looks
+// at.
+const estimateSampleSize = 64 << 10
+
+// incompressibleRatio is the estimate of EstimateCompressible above
+// which WithCodec stores the data of a block raw.
+const incompressibleRatio = 0.9
+
+// EstimateCompressible cheaply estimates how well data would compress,
+// as the expected ratio of compressed to original size: values near 1
+// mean incompressible data, and lower values mean more compressible
+// data. The estimate is the order-0 entropy of the bytes of a sample
+// of data, so it does not account for repeated sequences and tends to
+// be pessimistic for structured data. Empty data yields 1.
+func EstimateCompressible(data []byte) float64 {
+	if len(data) > estimateSampleSize {
+		// Sample evenly spread chunks rather than only the start.
+		const numChunks = 16
+		const chunkSize = estimateSampleSize / numChunks
+		step := (len(data) - chunkSize) / (numChunks - 1)
+		var counts [256]int
+		for i := 0; i < numChunks; i++ {
+			for _, b := range data[i*step : i*step+chunkSize] {
+				counts[b]++
+			}
+		}
+		return entropyRatio(&counts, estimateSampleSize)
+	}
+	if len(data) == 0 {
+		return 1
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	return entropyRatio(&counts, len(data))
+}
+
+// entropyRatio returns the order-0 entropy of the byte counts, in bits
+// per byte, divided by 8.
+func entropyRatio(counts *[256]int, total int) float64 {
+	var entropy float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy / 8
+}
+
+// Codec is a compression format of block data. It is recorded in block
+// headers when enabled by WithBlockCodecs.
+type Codec uint8
+
+const (
+	// CodecNone means that the block data is not compressed.
+	CodecNone Codec = iota
+	// CodecFlate means that the block data is in the raw DEFLATE
+	// format of compress/flate.
+	CodecFlate
+	// CodecGzip means that the block data is in the format of
+	// compress/gzip.
+	CodecGzip
+)
+
+func (c Codec) String() string {
+	switch c {
+	case CodecNone:
+		return "none"
+	case CodecFlate:
+		return "flate"
+	case CodecGzip:
+		return "gzip"
+	}
+	return "unknown"
+}
+
+// WriteCodec compresses data with codec and writes the result as a
+// block with the given alignment, which is then sealed with
+// WithEncryption. The writer must have been created with
+// WithBlockCodecs; otherwise ErrBlockCodecsDisabled is returned.
+func (w *ByteBlockWriter) WriteCodec(data []byte, codec Codec, align int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.opts.blockCodecs {
+		w.err = ErrBlockCodecsDisabled
+		return w.err
+	}
+	if data, w.err = compress(codec, data); w.err != nil {
+		return w.err
+	}
+	if w.err = w.newBlock(align, int64(len(data)), blockMeta{flags: uint64(codec) << flagsCodecShift}); w.err != nil {
+		return w.err
+	}
+	return w.append(data)
+}
+
+// SliceCodec is like Slice except that it decompresses the block data
+// according to the codec recorded in the header, which it also
+// returns. Compressed blocks are decompressed into a new slice, while
+// raw blocks share memory with the slicer as usual. The slicer must
+// have been created with WithBlockCodecs or WithCodec; otherwise
+// ErrBlockCodecsDisabled is returned. A block that fails to decompress
+// is still consumed.
+func (r *ByteBlockSlicer) SliceCodec() ([]byte, Codec, error) {
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	if !r.opts.blockCodecs {
+		return nil, 0, ErrBlockCodecsDisabled
+	}
+	h, _, data, err := r.slice()
+	if err != nil {
+		return nil, 0, err
+	}
+	codec := h.codec()
+	if r.opts.codec == CodecNone {
+		// Otherwise slice has already decompressed the data.
+		if data, err = decompress(codec, data, r.opts.maxLength); err != nil {
+			return nil, codec, err
+		}
+	}
+	return data, codec, nil
+}
+
+// pendingBlock is a block whose data is gathered before being
+// compressed or sealed as a whole, see WithCodec and WithEncryption.
+type pendingBlock struct {
+	align int64
+	meta  blockMeta
+	data  []byte
+}
+
+// flushPending compresses and seals the pending block, whose data is
+// complete, and writes it.
+func (w *ByteBlockWriter) flushPending() error {
+	p := w.pending
+	w.pending = nil
+	w.inBlock = false
+	data, flags, err := w.opts.encodeData(p.data, p.meta.flags, w.numBytesWritten)
+	if err != nil {
+		w.err = err
+		return w.err
+	}
+	// The data may have been left raw, which must not make it pending
+	// again.
+	p.meta.flags, p.meta.raw = flags, true
+	if w.err = w.newBlock(p.align, int64(len(data)), p.meta); w.err != nil {
+		return w.err
+	}
+	return w.writeData(data)
+}
+
+// compress returns data compressed with codec.
+func compress(codec Codec, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	switch codec {
+	case CodecNone:
+		return data, nil
+	case CodecFlate:
+		zw, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case CodecGzip:
+		zw = gzip.NewWriter(&buf)
+	default:
+		return nil, ErrUnknownCodec
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress returns data decompressed with codec, failing with
+// ErrLengthLimit past maxLength bytes if positive.
+func decompress(codec Codec, data []byte, maxLength int64) ([]byte, error) {
+	if codec == CodecNone {
+		return data, nil
+	}
+	zr, err := decompressReader(codec, bytes.NewReader(data), maxLength)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+// decompressReader returns a reader of the data read from r
+// decompressed with codec. If maxLength is positive, the reader fails
+// with ErrLengthLimit past maxLength bytes of decompressed data, as
+// the limit of WithHeaderLimits only bounds the stored length.
+func decompressReader(codec Codec, r io.Reader, maxLength int64) (io.Reader, error) {
+	var zr io.Reader
+	switch codec {
+	case CodecNone:
+		return r, nil
+	case CodecFlate:
+		zr = flate.NewReader(r)
+	case CodecGzip:
+		var err error
+		if zr, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnknownCodec
+	}
+	if maxLength > 0 {
+		zr = &lengthLimiter{r: zr, left: maxLength}
+	}
+	return zr, nil
+}
+
+// lengthLimiter reads from r, failing with ErrLengthLimit once r has
+// more than left bytes.
+type lengthLimiter struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *lengthLimiter) Read(p []byte) (int, error) {
+	if l.left == 0 {
+		// Reading one more byte tells whether r ends at the limit.
+		var b [1]byte
+		n, err := io.ReadFull(l.r, b[:])
+		if n > 0 {
+			return 0, ErrLengthLimit
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+var (
+	ErrBlockCodecsDisabled = errors.New("block codecs are not enabled")
+	ErrUnknownCodec        = errors.New("unknown codec")
+)
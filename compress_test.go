@@ -0,0 +1,137 @@
+package byteblock
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestEstimateCompressible(t *testing.T) {
+	random := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 30000)
+	for _, i := range []struct {
+		Name     string
+		Data     []byte
+		Min, Max float64
+	}{
+		{"empty", nil, 1, 1},
+		{"zeros", make([]byte, 1000), 0, 0},
+		{"two symbols", bytes.Repeat([]byte("ab"), 500), 0.125, 0.125},
+		{"short random", random[:4096], 0.9, 1},
+		{"long random", random, 0.99, 1},
+		{"text", text, 0.4, 0.6},
+		{"short text", text[:1000], 0.4, 0.6},
+	} {
+		if r := EstimateCompressible(i.Data); r < i.Min || r > i.Max {
+			t.Errorf("%s: expected ratio in [%v, %v]; got %v", i.Name, i.Min, i.Max, r)
+		}
+	}
+}
+
+func TestSliceCodec(t *testing.T) {
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 100)
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithBlockCodecs())
+	for _, codec := range []Codec{CodecNone, CodecGzip, CodecFlate} {
+		if err := writer.WriteCodec(text, codec, 8); err != nil {
+			t.Fatalf("%v: unexpected error: %v", codec, err)
+		}
+	}
+	if buf.Len() >= 2*len(text) {
+		t.Errorf("expected compressed blocks; got %d bytes in total", buf.Len())
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithBlockCodecs())
+	for _, expected := range []Codec{CodecNone, CodecGzip, CodecFlate} {
+		data, codec, err := slicer.SliceCodec()
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", expected, err)
+		}
+		if codec != expected {
+			t.Errorf("expected %v; got %v", expected, codec)
+		}
+		if !bytes.Equal(data, text) {
+			t.Errorf("%v: got different data", expected)
+		}
+	}
+
+	if err := NewByteBlockWriter(&buf, WithBlockCodecs()).WriteCodec(text, 99, 8); err != ErrUnknownCodec {
+		t.Errorf("expected ErrUnknownCodec; got %v", err)
+	}
+	if err := NewByteBlockWriter(&buf).WriteCodec(text, CodecGzip, 8); err != ErrBlockCodecsDisabled {
+		t.Errorf("expected ErrBlockCodecsDisabled; got %v", err)
+	}
+	if _, _, err := NewByteBlockSlicer(buf.Bytes()).SliceCodec(); err != ErrBlockCodecsDisabled {
+		t.Errorf("expected ErrBlockCodecsDisabled; got %v", err)
+	}
+}
+
+func TestWithCodec(t *testing.T) {
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 100)
+	blocks := [][]byte{text, nil, []byte("short"), text[:1000]}
+	for _, codec := range []Codec{CodecFlate, CodecGzip} {
+		opts := []Option{WithCodec(codec), WithInlineNames(), WithChecksums()}
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		writer.Write(blocks[0], 8)
+		writer.WriteNamedInline("empty", blocks[1], 8)
+		writer.NewBlock(8, int64(len(blocks[2])))
+		writer.Append(blocks[2][:2])
+		writer.Append(blocks[2][2:])
+		writer.NewBlock(8, int64(len(blocks[3])))
+		if n, err := writer.ReadFrom(bytes.NewReader(blocks[3])); n != int64(len(blocks[3])) || err != nil {
+			t.Fatalf("%v: unexpected result: %d, %v", codec, n, err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("%v: unexpected error: %v", codec, err)
+		}
+		if buf.Len() >= len(text) {
+			t.Errorf("%v: expected compressed blocks; got %d bytes in total", codec, buf.Len())
+		}
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		for i, expected := range blocks {
+			if data, err := slicer.Slice(); !bytes.Equal(data, expected) || err != nil {
+				t.Errorf("%v: block %d: got %d bytes, %v", codec, i, len(data), err)
+			}
+			if data, err := reader.Read(); !bytes.Equal(data, expected) || err != nil {
+				t.Errorf("%v: block %d: got %d bytes, %v", codec, i, len(data), err)
+			}
+		}
+
+		slicer = NewByteBlockSlicer(buf.Bytes(), opts...)
+		for i, expected := range []Codec{codec, CodecNone, codec, codec} {
+			if data, c, err := slicer.SliceCodec(); c != expected || !bytes.Equal(data, blocks[i]) || err != nil {
+				t.Errorf("%v: block %d: expected %v; got %v, %d bytes, %v", codec, i, expected, c, len(data), err)
+			}
+		}
+	}
+}
+
+func TestCodecSkipsIncompressible(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 100)
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithCodec(CodecGzip))
+	writer.Write(random, 8)
+	writer.Write(text, 8)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), random) || bytes.Contains(buf.Bytes(), text) {
+		t.Errorf("expected only the random data to be stored raw")
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithCodec(CodecGzip))
+	for _, expected := range []struct {
+		Data  []byte
+		Codec Codec
+	}{{random, CodecNone}, {text, CodecGzip}} {
+		if data, codec, err := slicer.SliceCodec(); !bytes.Equal(data, expected.Data) || codec != expected.Codec || err != nil {
+			t.Errorf("expected %d bytes, %v; got %d bytes, %v, %v", len(expected.Data), expected.Codec, len(data), codec, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+)
+
+// Concat writes the blocks of srcs in order to dst as a single stream,
+// and returns the number of blocks written. All streams use opts. The
+// blocks are copied as CopyBlock does, so that the padding is
+// recomputed for the alignment of every block, up to 4096, to still
+// hold in the combined stream. The streams cannot have an index footer,
+// and ErrConcatIndexFooter is returned with WithIndexFooter. With
+// WithStreamMAC, the MAC of every stream is verified, and the combined
+// stream gets a MAC of its own.
+func Concat(dst io.Writer, srcs []io.Reader, opts ...Option) (int64, error) {
+	return Merge(dst, srcs, nil, opts...)
+}
+
+// Merge is like Concat except that only the blocks for which keep
+// returns true are written. keep is given the index of the source in
+// srcs and where the block was found in it; its Length is the length of
+// the data as stored, before any decompression. A nil keep keeps all
+// blocks.
+func Merge(dst io.Writer, srcs []io.Reader, keep func(src int, info BlockInfo) bool, opts ...Option) (int64, error) {
+	o := newOptions(opts)
+	if o.indexFooter {
+		return 0, ErrConcatIndexFooter
+	}
+	writer := NewByteBlockWriter(dst, opts...)
+	var n int64
+	for i, src := range srcs {
+		reader := NewByteBlockReader(src, opts...)
+		dst := func(c *blockCopy) (*ByteBlockWriter, error) {
+			if keep != nil && !keep(i, c.info) {
+				return nil, nil
+			}
+			return writer, nil
+		}
+		for {
+			copied, err := copyBlock(reader, dst)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return n, err
+			}
+			if copied {
+				n++
+			}
+		}
+	}
+	return n, writer.Close()
+}
+
+var ErrConcatIndexFooter = errors.New("cannot concatenate streams with an index footer")
@@ -0,0 +1,71 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"unsafe"
+)
+
+func TestConcat(t *testing.T) {
+	opts := []Option{WithMagicHeader(), WithChecksums(), WithInlineNames()}
+	var srcs []io.Reader
+	var expected []string
+	var aligns []int64
+	for i, blocks := range [][]string{{"hello", "world"}, {}, {"", "x", "wonderful"}} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for j, b := range blocks {
+			align := int64(1) << (i + 2*j)
+			writer.WriteString(b, align)
+			expected = append(expected, b)
+			aligns = append(aligns, align)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		srcs = append(srcs, bytes.NewReader(buf.Bytes()))
+	}
+
+	var dst bytes.Buffer
+	if n, err := Concat(&dst, srcs, opts...); n != int64(len(expected)) || err != nil {
+		t.Fatalf("expected %d blocks; got %d, %v", len(expected), n, err)
+	}
+	data := dst.Bytes()
+	slicer := NewByteBlockSlicer(data, opts...)
+	for i, e := range expected {
+		block, err := slicer.Slice()
+		if string(block) != e || err != nil {
+			t.Errorf("expected %q; got %q, %v", e, block, err)
+		}
+		if pos := uintptr(unsafe.Pointer(&block[:1][0])) - uintptr(unsafe.Pointer(&data[0])); pos%uintptr(aligns[i]) != 0 {
+			t.Errorf("block %d: expected alignment %d", i, aligns[i])
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	// Keep only the first block of each source.
+	for _, src := range srcs {
+		src.(*bytes.Reader).Seek(0, io.SeekStart)
+	}
+	dst.Reset()
+	keep := func(src int, info BlockInfo) bool { return info.Index == 0 }
+	if n, err := Merge(&dst, srcs, keep, opts...); n != 2 || err != nil {
+		t.Fatalf("expected 2 blocks; got %d, %v", n, err)
+	}
+	slicer = NewByteBlockSlicer(dst.Bytes(), opts...)
+	for _, e := range []string{"hello", ""} {
+		if block, err := slicer.Slice(); string(block) != e || err != nil {
+			t.Errorf("expected %q; got %q, %v", e, block, err)
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	if _, err := Concat(io.Discard, nil, WithIndexFooter()); err != ErrConcatIndexFooter {
+		t.Errorf("expected ErrConcatIndexFooter; got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package byteblock
+
+import (
+	"context"
+	"io"
+)
+
+// contextChunkSize is the number of bytes written or read at once by
+// the context-aware methods, between checks for cancellation.
+const contextChunkSize = 64 << 10
+
+// contextWriter writes to w in chunks, and fails with ctx.Err() once
+// ctx is done.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c contextWriter) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if err := c.ctx.Err(); err != nil {
+			return n, err
+		}
+		m, err := c.w.Write(p[n:min(n+contextChunkSize, len(p))])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// contextReader reads from r in chunks, and fails with ctx.Err() once
+// ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p[:min(len(p), contextChunkSize)])
+}
+
+// withContext runs f with the underlying writer of w wrapped in a
+// contextWriter.
+func (w *ByteBlockWriter) withContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	writer := w.writer
+	w.writer = contextWriter{ctx, writer}
+	defer func() { w.writer = writer }()
+	return f()
+}
+
+// NewBlockContext is like NewBlock except that writing the header and
+// the padding stops once ctx is done, which is checked between chunks
+// of 64KB. If ctx is done before anything is written, ctx.Err() is
+// returned and the writer can still be used; otherwise, the error is
+// kept by the writer like any error of the underlying writer, as the
+// stream is left incomplete.
+func (w *ByteBlockWriter) NewBlockContext(ctx context.Context, align int64, length int64) error {
+	return w.withContext(ctx, func() error { return w.NewBlock(align, length) })
+}
+
+// AppendContext is like Append except that writing stops once ctx is
+// done, as NewBlockContext does.
+func (w *ByteBlockWriter) AppendContext(ctx context.Context, data []byte) error {
+	return w.withContext(ctx, func() error { return w.Append(data) })
+}
+
+// SliceContext is like Slice except that it returns ctx.Err() without
+// slicing if ctx is done. As the slicer does no I/O, this lets loops
+// over many blocks stop early.
+func (r *ByteBlockSlicer) SliceContext(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Slice()
+}
+
+// ReadContext is like Read except that reading stops once ctx is done,
+// which is checked between chunks of 64KB. If ctx is done before
+// anything is read, ctx.Err() is returned and the reader can still be
+// used; otherwise, the error is kept by the reader, as the block is
+// left partially read.
+func (r *ByteBlockReader) ReadContext(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	reader := r.reader
+	r.reader = contextReader{ctx, reader}
+	defer func() { r.reader = reader }()
+	return r.Read()
+}
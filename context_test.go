@@ -0,0 +1,78 @@
+package byteblock
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithChecksums())
+	big := bytes.Repeat([]byte("x"), 3*contextChunkSize+1)
+	if err := writer.NewBlockContext(ctx, 4096, int64(len(big))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writer.AppendContext(ctx, big); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Canceling before anything is written leaves the writer usable.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := writer.NewBlockContext(canceled, 8, 5); err != context.Canceled {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+	writer.NewBlock(8, 5)
+	if err := writer.AppendContext(canceled, []byte("hello")); err != context.Canceled {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+	if err := writer.Append([]byte("hello")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithChecksums())
+	if _, err := slicer.SliceContext(canceled); err != context.Canceled {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+	for _, expected := range [][]byte{big, []byte("hello")} {
+		if data, err := slicer.SliceContext(ctx); !bytes.Equal(data, expected) || err != nil {
+			t.Errorf("expected %d bytes; got %d, %v", len(expected), len(data), err)
+		}
+	}
+
+	reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), WithChecksums())
+	if _, err := reader.ReadContext(canceled); err != context.Canceled {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+	for _, expected := range [][]byte{big, []byte("hello")} {
+		if data, err := reader.ReadContext(ctx); !bytes.Equal(data, expected) || err != nil {
+			t.Errorf("expected %d bytes; got %d, %v", len(expected), len(data), err)
+		}
+	}
+
+	// Canceling in the middle of a block stops the writer.
+	ctx, cancel = context.WithCancel(ctx)
+	writer = NewByteBlockWriter(&cancelWriter{cancel: cancel})
+	writer.NewBlock(8, int64(len(big)))
+	if err := writer.AppendContext(ctx, big); err != context.Canceled {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+	if err := writer.Close(); err != context.Canceled {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+}
+
+// cancelWriter calls cancel at the first write of data.
+type cancelWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	if len(p) == contextChunkSize {
+		w.cancel()
+	}
+	return w.Buffer.Write(p)
+}
@@ -0,0 +1,195 @@
+package byteblock
+
+import (
+	"hash/crc32"
+	"io"
+)
+
+// maxInferredAlign caps the alignment Convert gives to blocks.
+const maxInferredAlign = 4096
+
+// Convert slices all remaining blocks out of src and writes them to
+// dst, returning the number of blocks written, so that a stream can be
+// re-encoded with different options. Block data is kept as is, apart
+// from compression with WithCodec, and so are inline names and header
+// flags as far as the options of dst support them. Since headers do
+// not record the requested alignment except with WithAlignInHeader,
+// each block is otherwise aligned to the largest power of two, up to
+// 4096, that its data position in src is a multiple of. Blocks are
+// sealed with the WithEncryption of dst, if any; src must be able to
+// open sealed blocks, otherwise ErrBlockEncrypted is returned. A block
+// that src leaves compressed can only be written to a dst created with
+// WithBlockCodecs or an option implying it; otherwise
+// ErrBlockCodecsDisabled is returned.
+func Convert(dst *ByteBlockWriter, src *ByteBlockSlicer) (int, error) {
+	n := 0
+	for {
+		headerPos := src.numBytesSliced
+		h, name, data, err := src.slice()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if h.encrypted() && src.opts.keys == nil {
+			// Sealed data cannot be moved without being opened.
+			return n, ErrBlockEncrypted
+		}
+		var align int64
+		if src.opts.offsetMode == offsetAlign {
+			_, offsetPos := src.opts.fieldPos()
+			align = src.opts.readField(src.data[headerPos+int64(offsetPos):])
+		} else {
+			align = inferAlign(src.basePos + headerPos + src.opts.headerSize() + h.padding + h.length - int64(len(data)))
+		}
+		flags := h.flags
+		if src.opts.codec != CodecNone {
+			// The data has been decompressed.
+			flags &^= 0xff << flagsCodecShift
+		}
+		if src.opts.keys != nil {
+			// The data has been opened.
+			flags &^= flagsEncrypted | 0xff<<flagsKeyShift
+		}
+		if flags&flagsTransformed != 0 && !dst.opts.blockCodecs {
+			// The data would lose its codec.
+			return n, ErrBlockCodecsDisabled
+		}
+		meta := blockMeta{name: string(name)}
+		if dst.opts.hasFlags() {
+			meta.flags = flags
+		}
+		if err := dst.newBlock(align, int64(len(data)), meta); err != nil {
+			return n, err
+		}
+		if err := dst.append(data); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// inferAlign returns the largest power of two, up to maxInferredAlign,
+// that the data position pos is a multiple of.
+func inferAlign(pos int64) int64 {
+	align := pos & -pos
+	if align == 0 || align > maxInferredAlign {
+		align = maxInferredAlign
+	}
+	return align
+}
+
+// CopyBlock transfers the next block of src to dst verbatim, streaming
+// its data without decompressing or recompressing it, such as to
+// filter, merge or repack streams cheaply. The alignment is kept as
+// Convert does, and so are the inline name and the header flags as far
+// as the options of dst support them. A compressed block can only be
+// copied to a writer created with WithBlockCodecs or an option implying
+// it; otherwise ErrBlockCodecsDisabled is returned. As sealed data is
+// bound to the position of its block, a sealed block is opened with the
+// WithEncryption of src, and blocks are sealed again with that of dst,
+// if any; the data of such blocks is gathered in memory. The checksums
+// of src are verified once the data is copied. io.EOF is returned if
+// src has no more blocks.
+func CopyBlock(dst *ByteBlockWriter, src *ByteBlockReader) error {
+	_, err := copyBlock(src, func(*blockCopy) (*ByteBlockWriter, error) { return dst, nil })
+	return err
+}
+
+// blockCopy describes a block being copied by copyBlock.
+type blockCopy struct {
+	info  BlockInfo // where the block was found in the source
+	align int64
+	meta  blockMeta
+}
+
+// copyBlock is like CopyBlock except that the destination of the block
+// is chosen by dst once its header is read; the block is skipped
+// instead if dst returns nil. It tells whether the block was copied.
+func copyBlock(src *ByteBlockReader, dst func(*blockCopy) (*ByteBlockWriter, error)) (bool, error) {
+	h, err := src.readHeader()
+	if err != nil {
+		return false, err
+	}
+	c := &blockCopy{info: BlockInfo{Index: src.numBlocks, Offset: src.blockPos, Padding: h.padding}}
+	src.numBlocks++
+	b := &blockReader{r: src, left: h.length}
+	if src.opts.checksums {
+		b.payloadHash = crc32.New(castagnoliTable)
+	}
+	src.block = b
+	if src.opts.inlineNames {
+		if c.meta.name, err = b.readName(); err != nil {
+			return false, err
+		}
+	}
+	c.info.DataOffset, c.info.Length = src.numBytesRead, b.left
+	if src.opts.offsetMode == offsetAlign {
+		_, offsetPos := src.opts.fieldPos()
+		c.align = src.opts.readField(src.header[offsetPos:])
+	} else {
+		c.align = inferAlign(c.info.DataOffset)
+	}
+	// The data is never compressed again by newBlock.
+	c.meta.raw = true
+	w, err := dst(c)
+	if err != nil {
+		return false, err
+	}
+	if w == nil {
+		return false, src.skipBlock()
+	}
+	if w.err != nil {
+		return false, w.err
+	}
+	if h.encrypted() || w.opts.keys != nil && b.left > 0 {
+		if err := resealBlock(src, h, b, w, c); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if h.flags&flagsTransformed != 0 && !w.opts.blockCodecs {
+		return false, ErrBlockCodecsDisabled
+	}
+	if w.opts.hasFlags() {
+		c.meta.flags = h.flags
+	}
+	if err := w.newBlock(c.align, b.left, c.meta); err != nil {
+		return false, err
+	}
+	if err := w.AppendFrom(b, b.left); err != nil {
+		return false, err
+	}
+	return true, src.skipBlock()
+}
+
+// resealBlock copies the rest of the block of src with header h, whose
+// data is read by b, to w as copyBlock does, opening its data with the
+// options of src if it is sealed and sealing it with those of w.
+func resealBlock(src *ByteBlockReader, h header, b *blockReader, w *ByteBlockWriter, c *blockCopy) error {
+	data, err := io.ReadAll(b)
+	if err != nil {
+		return err
+	}
+	if data, err = src.opts.openData(h, data); err != nil {
+		return err
+	}
+	flags := h.flags &^ (flagsEncrypted | 0xff<<flagsKeyShift)
+	if flags&flagsTransformed != 0 && !w.opts.blockCodecs {
+		return ErrBlockCodecsDisabled
+	}
+	if w.opts.hasFlags() {
+		c.meta.flags = flags
+	}
+	if data, c.meta.flags, err = w.opts.sealData(data, c.meta.flags, w.numBytesWritten); err != nil {
+		return err
+	}
+	if err := w.newBlock(c.align, int64(len(data)), c.meta); err != nil {
+		return err
+	}
+	if err := w.append(data); err != nil {
+		return err
+	}
+	return src.skipBlock()
+}
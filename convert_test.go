@@ -0,0 +1,136 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"unsafe"
+)
+
+func TestConvert(t *testing.T) {
+	blocks := []string{"hello", "", "wonderful", "world"}
+	aligns := []int64{8, 1, 64, 16}
+	var src bytes.Buffer
+	writer := NewByteBlockWriter(&src, WithInlineNames(), WithBlockVersions())
+	for i, b := range blocks {
+		writer.NewBlockVer(uint8(i), aligns[i], int64(len(b)))
+		writer.AppendString(b)
+	}
+
+	var dst bytes.Buffer
+	slicer := NewByteBlockSlicer(src.Bytes(), WithInlineNames(), WithBlockVersions())
+	n, err := Convert(NewByteBlockWriter(&dst, WithAlignInHeader(), WithHeaderFieldOrder(true), WithBlockVersions()), slicer)
+	if n != len(blocks) || err != nil {
+		t.Fatalf("expected %d blocks; got %d, %v", len(blocks), n, err)
+	}
+	if bytes.Equal(src.Bytes(), dst.Bytes()) {
+		t.Errorf("expected different encodings")
+	}
+
+	data := dst.Bytes()
+	slicer = NewByteBlockSlicer(data, WithAlignInHeader(), WithHeaderFieldOrder(true), WithBlockVersions())
+	for i, expected := range blocks {
+		block, version, err := slicer.SliceVer()
+		if string(block) != expected || version != uint8(i) || err != nil {
+			t.Errorf("expected %q, %d; got %q, %d, %v", expected, i, block, version, err)
+		}
+		if pos := uintptr(unsafe.Pointer(&block[:1][0])) - uintptr(unsafe.Pointer(&data[0])); pos%uintptr(aligns[i]) != 0 {
+			t.Errorf("block %d: expected alignment %d", i, aligns[i])
+		}
+	}
+	if !slicer.AtEnd() {
+		t.Errorf("expected the whole stream to be sliced")
+	}
+
+	// Compressed data left as is by the slicer keeps its codec, which dst
+	// must be able to record.
+	src.Reset()
+	writer = NewByteBlockWriter(&src, WithBlockCodecs())
+	writer.WriteCodec(bytes.Repeat([]byte("compressible "), 100), CodecGzip, 8)
+	writer.Close()
+	if _, err := Convert(NewByteBlockWriter(io.Discard), NewByteBlockSlicer(src.Bytes(), WithBlockCodecs())); err != ErrBlockCodecsDisabled {
+		t.Errorf("expected ErrBlockCodecsDisabled; got %v", err)
+	}
+	dst.Reset()
+	if n, err := Convert(NewByteBlockWriter(&dst, WithBlockCodecs()), NewByteBlockSlicer(src.Bytes(), WithBlockCodecs())); n != 1 || err != nil {
+		t.Fatalf("expected 1 block; got %d, %v", n, err)
+	}
+	if block, codec, err := NewByteBlockSlicer(dst.Bytes(), WithBlockCodecs()).SliceCodec(); len(block) != 1300 || codec != CodecGzip || err != nil {
+		t.Errorf("expected 1300 bytes, %v; got %d, %v, %v", CodecGzip, len(block), codec, err)
+	}
+}
+
+func TestCopyBlock(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+	blocks := []string{"hello", "", "wonderful", "world"}
+	aligns := []int64{8, 1, 64, 16}
+	srcOpts := []Option{WithInlineNames(), WithChecksums(), WithCodec(CodecGzip)}
+	var src bytes.Buffer
+	writer := NewByteBlockWriter(&src, srcOpts...)
+	for i, b := range blocks {
+		writer.WriteNamedInline(names[i], []byte(b), aligns[i])
+	}
+
+	// Drop the third block.
+	dstOpts := []Option{WithInlineNames(), WithBlockCodecs(), WithBackLink()}
+	var dst bytes.Buffer
+	reader := NewByteBlockReader(bytes.NewReader(src.Bytes()), srcOpts...)
+	dstWriter := NewByteBlockWriter(&dst, dstOpts...)
+	for i := range blocks {
+		var err error
+		if i == 2 {
+			err = reader.Skip()
+		} else {
+			err = CopyBlock(dstWriter, reader)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := CopyBlock(dstWriter, reader); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+	if err := dstWriter.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := dst.Bytes()
+	slicer := NewByteBlockSlicer(data, dstOpts...)
+	for _, i := range []int{0, 1, 3} {
+		// The data is still compressed.
+		name, block, err := slicer.SliceNamed()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pos := uintptr(unsafe.Pointer(&block[:1][0])) - uintptr(unsafe.Pointer(&data[0])); pos%uintptr(aligns[i]) != 0 {
+			t.Errorf("block %d: expected alignment %d", i, aligns[i])
+		}
+		if len(block) > 0 {
+			if block, err = decompress(CodecGzip, block, 0); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if name != names[i] || string(block) != blocks[i] {
+			t.Errorf("expected %q, %q; got %q, %q", names[i], blocks[i], name, block)
+		}
+	}
+
+	// Compressed blocks need codecs in the destination.
+	reader = NewByteBlockReader(bytes.NewReader(src.Bytes()), srcOpts...)
+	if err := CopyBlock(NewByteBlockWriter(io.Discard), reader); err != ErrBlockCodecsDisabled {
+		t.Errorf("expected ErrBlockCodecsDisabled; got %v", err)
+	}
+
+	// Corrupted data is caught.
+	corrupted := bytes.Clone(src.Bytes())
+	corrupted[len(corrupted)-10] ^= 1
+	reader = NewByteBlockReader(bytes.NewReader(corrupted), srcOpts...)
+	dstWriter = NewByteBlockWriter(io.Discard, dstOpts...)
+	var err error
+	for err == nil {
+		err = CopyBlock(dstWriter, reader)
+	}
+	if err == io.EOF {
+		t.Errorf("expected a checksum error")
+	}
+}
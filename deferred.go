@@ -0,0 +1,83 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// unknownLength is the length that a block started by
+// NewBlockUnknownLength has until EndBlock. It leaves room for the
+// inline name within the header field.
+const unknownLength = math.MaxInt64 / 2
+
+// NewBlockUnknownLength starts a new block with the given alignment
+// whose length is not known yet, such as one holding the output of a
+// compressor. Its data is appended as usual, and EndBlock finishes it
+// by seeking back to patch the length into the header. The underlying
+// writer must be an io.WriteSeeker; otherwise ErrNotSeekable is
+// returned. The length cannot be deferred with WithFrameChecksum,
+// WithFixedStride, WithCodec, WithEncryption or WithStreamMAC, which
+// need it before the data, and ErrDeferredLengthUnsupported is returned
+// with those.
+func (w *ByteBlockWriter) NewBlockUnknownLength(align int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.opts.frameChecksum || w.opts.stride > 0 || w.opts.transformsData() || w.opts.macKey != nil {
+		return ErrDeferredLengthUnsupported
+	}
+	ws, ok := w.writer.(io.WriteSeeker)
+	if !ok {
+		return ErrNotSeekable
+	}
+	pos, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if err := w.newBlock(align, unknownLength, blockMeta{}); err != nil {
+		return err
+	}
+	w.deferredPos = pos
+	w.deferred = true
+	return nil
+}
+
+// EndBlock finishes the block started by NewBlockUnknownLength with
+// the data appended so far. ErrNoDeferredBlock is returned if there is
+// no such block.
+func (w *ByteBlockWriter) EndBlock() error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.deferred {
+		return ErrNoDeferredBlock
+	}
+	w.deferred = false
+	nameSize := w.opts.inlineNameSize("")
+	length := unknownLength + nameSize - w.numBytesLeft
+	ws := w.writer.(io.WriteSeeker)
+	lengthPos, _ := w.opts.fieldPos()
+	endPos := w.deferredPos + w.numBytesWritten - w.blockPos
+	if _, w.err = ws.Seek(w.deferredPos+int64(lengthPos), io.SeekStart); w.err != nil {
+		return w.err
+	}
+	w.fillField(length)
+	if _, w.err = ws.Write(w.stub[:]); w.err != nil {
+		return w.err
+	}
+	if _, w.err = ws.Seek(endPos, io.SeekStart); w.err != nil {
+		return w.err
+	}
+	if w.index != nil {
+		w.index[len(w.index)-1].Length = length - nameSize
+	}
+	w.numBytesLeft = 0
+	return w.finishBlock()
+}
+
+var (
+	ErrNotSeekable               = errors.New("writer is not seekable")
+	ErrDeferredLengthUnsupported = errors.New("block length cannot be deferred with the options")
+	ErrNoDeferredBlock           = errors.New("no block of unknown length is started")
+)
@@ -0,0 +1,84 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewBlockUnknownLength(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithChecksums(), WithBackLink(), WithInlineNames()},
+		{WithIndexFooter(), WithHeaderFieldOrder(true)},
+	} {
+		f, err := os.Create(filepath.Join(t.TempDir(), "blocks"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		// The stream need not start at the beginning of the file.
+		f.WriteString("prefix")
+		writer := NewByteBlockWriter(f, opts...)
+		writer.Write([]byte("first"), 8)
+		if err := writer.NewBlockUnknownLength(16); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		writer.AppendString("hello ")
+		writer.AppendString("world")
+		if err := writer.EndBlock(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := writer.EndBlock(); err != ErrNoDeferredBlock {
+			t.Errorf("expected ErrNoDeferredBlock; got %v", err)
+		}
+		writer.NewBlockUnknownLength(8)
+		writer.EndBlock()
+		writer.Write([]byte("last"), 8)
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = data[len("prefix"):]
+		expected := []string{"first", "hello world", "", "last"}
+		slicer := NewByteBlockSlicer(data, opts...)
+		for _, e := range expected {
+			if block, err := slicer.Slice(); string(block) != e || err != nil {
+				t.Errorf("expected %q; got %q, %v", e, block, err)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+		reader := NewByteBlockReaderAt(bytes.NewReader(data), int64(len(data)), opts...)
+		for i, e := range expected {
+			if block, err := reader.ReadBlock(int64(i)); string(block) != e || err != nil {
+				t.Errorf("block %d: expected %q; got %q, %v", i, e, block, err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewByteBlockWriter(&buf).NewBlockUnknownLength(8); err != ErrNotSeekable {
+		t.Errorf("expected ErrNotSeekable; got %v", err)
+	}
+	f, err := os.Create(filepath.Join(t.TempDir(), "blocks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := NewByteBlockWriter(f, WithFrameChecksum()).NewBlockUnknownLength(8); err != ErrDeferredLengthUnsupported {
+		t.Errorf("expected ErrDeferredLengthUnsupported; got %v", err)
+	}
+	writer := NewByteBlockWriter(f)
+	writer.NewBlockUnknownLength(8)
+	if err := writer.Close(); err != ErrBlockNotFinished {
+		t.Errorf("expected ErrBlockNotFinished; got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package byteblock
+
+import "io"
+
+// DiffKind describes how two blocks differ.
+type DiffKind int
+
+const (
+	// DiffMissingBlock means that one of the streams ends before the
+	// other.
+	DiffMissingBlock DiffKind = iota + 1
+	// DiffLength means that the blocks have different lengths.
+	DiffLength
+	// DiffAlignment means that the blocks have the same length but
+	// their data start at different positions.
+	DiffAlignment
+	// DiffPayload means that the blocks are framed identically but
+	// have different data.
+	DiffPayload
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffMissingBlock:
+		return "missing block"
+	case DiffLength:
+		return "length differs"
+	case DiffAlignment:
+		return "alignment differs"
+	case DiffPayload:
+		return "payload differs"
+	}
+	return "unknown"
+}
+
+// BlockDiff describes the first difference between two streams.
+type BlockDiff struct {
+	// Index is the index of the first differing block.
+	Index int
+	Kind  DiffKind
+}
+
+// Diff compares the streams a and b block by block and returns the
+// first difference, or nil if they contain identical blocks at
+// identical positions. Errors from slicing either stream are
+// returned.
+func Diff(a, b []byte, opts ...Option) (*BlockDiff, error) {
+	sa, sb := NewByteBlockSlicer(a, opts...), NewByteBlockSlicer(b, opts...)
+	for i := 0; ; i++ {
+		da, errA := sa.Slice()
+		if errA != nil && errA != io.EOF {
+			return nil, errA
+		}
+		db, errB := sb.Slice()
+		if errB != nil && errB != io.EOF {
+			return nil, errB
+		}
+		if errA == io.EOF && errB == io.EOF {
+			return nil, nil
+		}
+		var kind DiffKind
+		switch {
+		case errA == io.EOF || errB == io.EOF:
+			kind = DiffMissingBlock
+		case len(da) != len(db):
+			kind = DiffLength
+		case sa.numBytesSliced != sb.numBytesSliced:
+			kind = DiffAlignment
+		case string(da) != string(db):
+			kind = DiffPayload
+		default:
+			continue
+		}
+		return &BlockDiff{Index: i, Kind: kind}, nil
+	}
+}
@@ -0,0 +1,51 @@
+package byteblock
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type block struct {
+		Data  string
+		Align int64
+	}
+	write := func(blocks []block) []byte {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf)
+		for _, b := range blocks {
+			writer.WriteString(b.Data, b.Align)
+		}
+		return buf.Bytes()
+	}
+	base := []block{{"hello", 0}, {"world", 8}, {"hello", 16}, {"world", 32}}
+	a := write(base)
+
+	if d, err := Diff(a, write(base)); d != nil || err != nil {
+		t.Errorf("expected no difference; got %+v, %v", d, err)
+	}
+
+	for _, i := range []struct {
+		Blocks []block
+		Diff   *BlockDiff
+	}{
+		{[]block{{"hello", 0}, {"world", 8}, {"jello", 16}, {"world", 32}}, &BlockDiff{2, DiffPayload}},
+		{[]block{{"hello", 0}, {"world", 8}, {"hello!", 16}, {"world", 32}}, &BlockDiff{2, DiffLength}},
+		{[]block{{"hello", 0}, {"world", 8}, {"hello", 128}, {"world", 32}}, &BlockDiff{2, DiffAlignment}},
+		{[]block{{"hello", 0}, {"world", 8}}, &BlockDiff{2, DiffMissingBlock}},
+		{append(base, block{"!", 0}), &BlockDiff{4, DiffMissingBlock}},
+	} {
+		d, err := Diff(a, write(i.Blocks))
+		if err != nil {
+			t.Errorf("case %+v: unexpected error: %v", i.Blocks, err)
+		}
+		if !reflect.DeepEqual(d, i.Diff) {
+			t.Errorf("case %+v: expected %+v; got %+v", i.Blocks, i.Diff, d)
+		}
+	}
+
+	if _, err := Diff(a, a[:len(a)-1]); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
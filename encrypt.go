@@ -0,0 +1,180 @@
+package byteblock
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// KeyProvider provides the AES keys of WithEncryption, which are 16,
+// 24 or 32 bytes long for AES-128, AES-192 or AES-256. Each key has an
+// ID, recorded in the header of the blocks it seals, so that keys can
+// be rotated while blocks sealed with older keys remain readable.
+type KeyProvider interface {
+	// SealingKey returns the key to seal new blocks with, and its ID.
+	SealingKey() (id uint8, key []byte, err error)
+	// Key returns the key with the given ID, to open blocks.
+	Key(id uint8) ([]byte, error)
+}
+
+// StaticKey is a KeyProvider of a single key, whose ID is 0.
+type StaticKey []byte
+
+func (k StaticKey) SealingKey() (uint8, []byte, error) {
+	return 0, k, nil
+}
+
+func (k StaticKey) Key(id uint8) ([]byte, error) {
+	if id != 0 {
+		return nil, ErrUnknownKey
+	}
+	return k, nil
+}
+
+// Sealed block data starts with the nonce and ends with the tag.
+const (
+	nonceSize    = 12
+	tagSize      = 16
+	sealOverhead = nonceSize + tagSize
+)
+
+// flagsTransformed holds the header flags that tell how the block data
+// was compressed or sealed.
+const flagsTransformed = 0xff<<flagsCodecShift | 0xff<<flagsKeyShift | flagsEncrypted
+
+// transformsData tells whether the data of blocks is compressed or
+// sealed as a whole, see WithCodec and WithEncryption.
+func (o *options) transformsData() bool {
+	return o.codec != CodecNone || o.keys != nil
+}
+
+// plain returns a copy of the options that reads blocks as stored, for
+// the index footer and the MAC trailer, which are neither compressed
+// nor sealed.
+func (o *options) plain() options {
+	p := *o
+	p.codec, p.keys = CodecNone, nil
+	return p
+}
+
+// encodeData compresses and seals the data of a block whose header
+// is at pos, as enabled by the options, and returns the result along
+// with flags, the header flags of the block, updated to record how.
+// Data whose flags already record a codec is not compressed again.
+func (o *options) encodeData(data []byte, flags uint64, pos int64) ([]byte, uint64, error) {
+	data, flags, err := o.compressData(data, flags)
+	if err != nil {
+		return nil, 0, err
+	}
+	return o.sealData(data, flags, pos)
+}
+
+// compressData is like encodeData except that it only compresses.
+// Data that EstimateCompressible deems incompressible is kept raw, with
+// CodecNone in the flags.
+func (o *options) compressData(data []byte, flags uint64) ([]byte, uint64, error) {
+	if flags&(0xff<<flagsCodecShift) != 0 || o.codec == CodecNone {
+		return data, flags, nil
+	}
+	if EstimateCompressible(data) > incompressibleRatio {
+		return data, flags, nil
+	}
+	data, err := compress(o.codec, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, flags | uint64(o.codec)<<flagsCodecShift, nil
+}
+
+// sealData is like encodeData except that it only seals. The flags
+// and pos are authenticated along with the data, so that a sealed
+// block cannot be moved or have its flags altered unnoticed.
+func (o *options) sealData(data []byte, flags uint64, pos int64) ([]byte, uint64, error) {
+	if o.keys == nil || flags&flagsEncrypted != 0 {
+		return data, flags, nil
+	}
+	id, key, err := o.keys.SealingKey()
+	if err != nil {
+		return nil, 0, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	flags |= uint64(id)<<flagsKeyShift | flagsEncrypted
+	sealed := make([]byte, nonceSize, nonceSize+len(data)+tagSize)
+	if _, err := rand.Read(sealed); err != nil {
+		return nil, 0, err
+	}
+	return aead.Seal(sealed, sealed, data, sealAAD(flags, pos)), flags, nil
+}
+
+// decodeData opens and decompresses the data of a block with header h
+// as enabled by the options. ErrBlockEncrypted is returned for a sealed
+// block without WithEncryption, and ErrBlockNotEncrypted for a block
+// with data that is not sealed with WithEncryption.
+func (o *options) decodeData(h header, data []byte) ([]byte, error) {
+	data, err := o.openData(h, data)
+	if err != nil {
+		return nil, err
+	}
+	if o.codec == CodecNone {
+		return data, nil
+	}
+	return decompress(h.codec(), data, o.maxLength)
+}
+
+// openData is like decodeData except that it only opens.
+func (o *options) openData(h header, data []byte) ([]byte, error) {
+	if !h.encrypted() {
+		if o.keys != nil && len(data) > 0 {
+			return nil, ErrBlockNotEncrypted
+		}
+		return data, nil
+	}
+	if o.keys == nil {
+		return nil, ErrBlockEncrypted
+	}
+	key, err := o.keys.Key(h.keyID())
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < sealOverhead {
+		return nil, ErrOpenFailed
+	}
+	if data, err = aead.Open(nil, data[:nonceSize], data[nonceSize:], sealAAD(h.flags, h.pos)); err != nil {
+		return nil, ErrOpenFailed
+	}
+	return data, nil
+}
+
+// sealAAD returns the additional data authenticated along with sealed
+// block data: the header flags and position of the block.
+func sealAAD(flags uint64, pos int64) []byte {
+	var aad [16]byte
+	fillInt64(pos, aad[:8])
+	fillInt64(int64(flags), aad[8:])
+	return aad[:]
+}
+
+// newAEAD returns AES-GCM with key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var (
+	ErrUnknownKey            = errors.New("unknown key ID")
+	ErrBlockEncrypted        = errors.New("block is encrypted")
+	ErrBlockNotEncrypted     = errors.New("block is not encrypted")
+	ErrOpenFailed            = errors.New("block data cannot be decrypted or was altered")
+	ErrEncryptionUnsupported = errors.New("operation is not supported with encryption")
+)
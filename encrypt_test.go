@@ -0,0 +1,225 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// rotatingKeys seals with its last key and opens with any of them.
+type rotatingKeys [][]byte
+
+func (k rotatingKeys) SealingKey() (uint8, []byte, error) {
+	return uint8(len(k) - 1), k[len(k)-1], nil
+}
+
+func (k rotatingKeys) Key(id uint8) ([]byte, error) {
+	if int(id) >= len(k) {
+		return nil, ErrUnknownKey
+	}
+	return k[id], nil
+}
+
+func TestEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	blocks := []string{"secret", "", "another secret"}
+	for _, opts := range [][]Option{
+		{WithEncryption(StaticKey(key))},
+		{WithEncryption(StaticKey(key[:16])), WithCodec(CodecGzip), WithChecksums(), WithInlineNames()},
+		{WithEncryption(StaticKey(key)), WithMagicHeader(), WithIndexFooter()},
+		{WithEncryption(StaticKey(key)), WithStreamMAC(key), WithIndexFooter(), WithChecksums()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		var offsets []int64
+		for _, b := range blocks {
+			offsets = append(offsets, writer.NextHeaderOffset())
+			writer.WriteString(b, 16)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bytes.Contains(buf.Bytes(), []byte("secret")) {
+			t.Errorf("expected the data to be sealed")
+		}
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		streaming := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		readerAt := NewByteBlockReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), opts...)
+		for i, expected := range blocks {
+			if data, err := slicer.Slice(); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+			if data, err := reader.Read(); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+			r, err := streaming.NextReader()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data, err := io.ReadAll(r); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+			if data, _, err := readerAt.ReadBlockAt(offsets[i]); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+
+		// Without the key, or with the wrong one, the data stays sealed.
+		if _, err := NewByteBlockSlicer(buf.Bytes(), append(opts, WithEncryption(StaticKey(bytes.Repeat([]byte("x"), 32))))...).Slice(); err != ErrOpenFailed {
+			t.Errorf("expected ErrOpenFailed; got %v", err)
+		}
+	}
+
+	// Keys can be rotated.
+	keys := rotatingKeys{key}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithEncryption(keys))
+	writer.WriteString("old", 8)
+	writer.opts.keys = append(keys, bytes.Repeat([]byte("n"), 32))
+	writer.WriteString("new", 8)
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithEncryption(writer.opts.keys))
+	for _, expected := range []string{"old", "new"} {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	if err := NewByteBlockSlicer(buf.Bytes(), WithEncryption(keys)).Skip(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	slicer = NewByteBlockSlicer(buf.Bytes(), WithEncryption(keys))
+	slicer.Slice()
+	if _, err := slicer.Slice(); err != ErrUnknownKey {
+		t.Errorf("expected ErrUnknownKey; got %v", err)
+	}
+	if _, err := NewByteBlockSlicer(buf.Bytes(), WithCodec(CodecGzip)).Slice(); err != ErrBlockEncrypted {
+		t.Errorf("expected ErrBlockEncrypted; got %v", err)
+	}
+
+	// Altered data is caught.
+	tampered := bytes.Clone(buf.Bytes())
+	tampered[len(tampered)-1] ^= 1
+	slicer = NewByteBlockSlicer(tampered, WithEncryption(writer.opts.keys))
+	slicer.Slice()
+	if _, err := slicer.Slice(); err != ErrOpenFailed {
+		t.Errorf("expected ErrOpenFailed; got %v", err)
+	}
+
+	// Sealing has a fixed overhead, which layouts account for.
+	layout := NewLayout(WithEncryption(StaticKey(key)))
+	layout.Add(8, 3)
+	if size := layout.Size(); size != int64(buf.Len())/2 {
+		t.Errorf("expected size %d; got %d", buf.Len()/2, size)
+	}
+}
+
+func TestEncryptionPaths(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	opts := []Option{WithEncryption(StaticKey(key))}
+
+	// Blocks compressed by WriteCodec are sealed too.
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, opts...)
+	writer.WriteCodec([]byte("secret secret secret"), CodecFlate, 8)
+	writer.WriteString("secret", 8)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+	if data, codec, err := slicer.SliceCodec(); string(data) != "secret secret secret" || codec != CodecFlate || err != nil {
+		t.Errorf("expected %q, %v; got %q, %v, %v", "secret secret secret", CodecFlate, data, codec, err)
+	}
+	sealed := bytes.Clone(buf.Bytes())
+
+	// Sealed data is bound to the position and the flags of its block.
+	moved := append(bytes.Repeat([]byte{0}, 8), sealed...)
+	slicer = NewByteBlockSlicer(moved[8:], opts...)
+	slicer.basePos = 8
+	if _, err := slicer.Slice(); err != ErrOpenFailed {
+		t.Errorf("expected ErrOpenFailed; got %v", err)
+	}
+	altered := bytes.Clone(sealed)
+	altered[16+2] = byte(CodecGzip)
+	if _, err := NewByteBlockSlicer(altered, opts...).Slice(); err != ErrOpenFailed {
+		t.Errorf("expected ErrOpenFailed; got %v", err)
+	}
+
+	// NewBlockAt cannot seal.
+	writer = NewByteBlockWriter(io.Discard, opts...)
+	if err := writer.NewBlockAt(64, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := writer.NewBlockAt(128, 5); err != ErrEncryptionUnsupported {
+		t.Errorf("expected ErrEncryptionUnsupported; got %v", err)
+	}
+
+	// Blocks that are not sealed are rejected, unless empty.
+	buf.Reset()
+	writer = NewByteBlockWriter(&buf, WithBlockCodecs())
+	writer.WriteString("", 8)
+	writer.WriteString("plain", 8)
+	writer.Close()
+	slicer = NewByteBlockSlicer(buf.Bytes(), opts...)
+	reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+	streaming := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+	for _, expected := range []error{nil, ErrBlockNotEncrypted} {
+		if _, err := slicer.Slice(); err != expected {
+			t.Errorf("expected %v; got %v", expected, err)
+		}
+		if _, err := reader.Read(); err != expected {
+			t.Errorf("expected %v; got %v", expected, err)
+		}
+		if _, err := streaming.NextReader(); err != expected {
+			t.Errorf("expected %v; got %v", expected, err)
+		}
+	}
+
+	// Convert seals blocks that keep the codec of the source.
+	buf.Reset()
+	writer = NewByteBlockWriter(&buf, WithBlockCodecs())
+	writer.WriteCodec([]byte("secret secret secret"), CodecGzip, 8)
+	writer.Close()
+	var dst bytes.Buffer
+	writer = NewByteBlockWriter(&dst, opts...)
+	if n, err := Convert(writer, NewByteBlockSlicer(buf.Bytes(), WithBlockCodecs())); n != 1 || err != nil {
+		t.Fatalf("expected 1 block; got %d, %v", n, err)
+	}
+	if bytes.Contains(dst.Bytes(), buf.Bytes()[16+8:]) {
+		t.Errorf("expected the data to be sealed")
+	}
+	if data, codec, err := NewByteBlockSlicer(dst.Bytes(), opts...).SliceCodec(); string(data) != "secret secret secret" || codec != CodecGzip || err != nil {
+		t.Errorf("expected %q, %v; got %q, %v, %v", "secret secret secret", CodecGzip, data, codec, err)
+	}
+	if _, err := Convert(NewByteBlockWriter(io.Discard, WithBlockCodecs()), NewByteBlockSlicer(sealed, WithBlockCodecs())); err != ErrBlockEncrypted {
+		t.Errorf("expected ErrBlockEncrypted; got %v", err)
+	}
+
+	// CopyBlock seals blocks again at their new position, under the key
+	// of the destination.
+	otherOpts := []Option{WithEncryption(StaticKey(key[:16])), WithMagicHeader()}
+	dst.Reset()
+	writer = NewByteBlockWriter(&dst, otherOpts...)
+	reader = NewByteBlockReader(bytes.NewReader(sealed), opts...)
+	for {
+		if err := CopyBlock(writer, reader); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	writer.Close()
+	slicer = NewByteBlockSlicer(dst.Bytes(), otherOpts...)
+	if data, codec, err := slicer.SliceCodec(); string(data) != "secret secret secret" || codec != CodecFlate || err != nil {
+		t.Errorf("expected %q, %v; got %q, %v, %v", "secret secret secret", CodecFlate, data, codec, err)
+	}
+	if data, err := slicer.Slice(); string(data) != "secret" || err != nil {
+		t.Errorf("expected %q; got %q, %v", "secret", data, err)
+	}
+	if err := CopyBlock(NewByteBlockWriter(io.Discard, WithBlockCodecs()), NewByteBlockReader(bytes.NewReader(sealed), WithBlockCodecs())); err != ErrBlockEncrypted {
+		t.Errorf("expected ErrBlockEncrypted; got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package byteblock
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SyncPolicy tells when a FileWriter commits the written blocks to
+// stable storage before Close, which always does. The zero value only
+// syncs at Close.
+type SyncPolicy struct {
+	// EveryBlock syncs after every block.
+	EveryBlock bool
+	// EveryBytes, if positive, syncs after a block once at least that
+	// many bytes were written since the last sync.
+	EveryBytes int64
+}
+
+// FileWriter writes a stream to a file that only appears once it is
+// complete. Blocks are written to a temporary file next to it, which
+// Close syncs and renames into place, so that readers never see a
+// partially written file, even after a crash.
+type FileWriter struct {
+	*ByteBlockWriter
+	file *atomicFile
+}
+
+// CreateFile creates a FileWriter of the file at path, which is
+// replaced at Close if it exists. Abort must be called to remove the
+// temporary file if the stream is given up.
+func CreateFile(path string, policy SyncPolicy, opts ...Option) (*FileWriter, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	file := &atomicFile{f: f, path: path}
+	writer := NewByteBlockWriter(f, opts...)
+	writer.flusher = &fileSyncer{w: writer, f: f, policy: policy}
+	writer.closer = file
+	return &FileWriter{ByteBlockWriter: writer, file: file}, nil
+}
+
+// Abort removes the temporary file, leaving any file at the path
+// alone. It does nothing after a successful Close.
+func (w *FileWriter) Abort() error {
+	if w.file.done {
+		return nil
+	}
+	w.file.done = true
+	w.err = ErrWriterClosed
+	w.file.f.Close()
+	return os.Remove(w.file.f.Name())
+}
+
+// fileSyncer syncs a file according to a SyncPolicy whenever the
+// writer flushes, that is after every block.
+type fileSyncer struct {
+	w      *ByteBlockWriter
+	f      *os.File
+	policy SyncPolicy
+	synced int64 // numBytesWritten at the last sync
+}
+
+func (s *fileSyncer) Flush() error {
+	n := s.w.numBytesWritten - s.synced
+	if n == 0 || !s.policy.EveryBlock && (s.policy.EveryBytes <= 0 || n < s.policy.EveryBytes) {
+		return nil
+	}
+	s.synced = s.w.numBytesWritten
+	return s.f.Sync()
+}
+
+// atomicFile is a temporary file that is renamed to path on Close.
+type atomicFile struct {
+	f    *os.File
+	path string
+	done bool
+}
+
+func (a *atomicFile) Close() error {
+	a.done = true
+	if err := a.f.Sync(); err != nil {
+		return err
+	}
+	if err := a.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(a.f.Name(), a.path); err != nil {
+		return err
+	}
+	// Make the rename itself durable. Not all platforms can sync
+	// directories, so this is best effort.
+	if d, err := os.Open(filepath.Dir(a.path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
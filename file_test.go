@@ -0,0 +1,76 @@
+package byteblock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocks")
+	os.WriteFile(path, []byte("old"), 0o644)
+
+	writer, err := CreateFile(path, SyncPolicy{}, WithChecksums())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writer.WriteString("hello", 8)
+	writer.WriteString("world", 8)
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("expected the old file before Close; got %q", data)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Count(data, WithChecksums()); n != 2 || err != nil {
+		t.Errorf("expected 2 blocks; got %d, %v", n, err)
+	}
+	if err := writer.Abort(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	writer, _ = CreateFile(path, SyncPolicy{EveryBytes: 1 << 20})
+	writer.WriteString("dropped", 8)
+	if err := writer.Abort(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := writer.WriteString("more", 8); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed; got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the file; got %v", entries)
+	}
+	data, _ = os.ReadFile(path)
+	if n, _ := Count(data, WithChecksums()); n != 2 {
+		t.Errorf("expected the previous file; got %d blocks", n)
+	}
+}
+
+func TestSyncPolicy(t *testing.T) {
+	for _, i := range []struct {
+		Policy SyncPolicy
+		Syncs  []int64
+	}{
+		{SyncPolicy{}, []int64{0, 0, 0}},
+		{SyncPolicy{EveryBlock: true}, []int64{24, 48, 72}},
+		{SyncPolicy{EveryBytes: 40}, []int64{0, 48, 48}},
+	} {
+		writer, err := CreateFile(filepath.Join(t.TempDir(), "blocks"), i.Policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		syncer := writer.flusher.(*fileSyncer)
+		for j, synced := range i.Syncs {
+			writer.WriteString("12345678", 8)
+			if syncer.synced != synced {
+				t.Errorf("%+v: block %d: expected a sync at %d; got %d", i.Policy, j, synced, syncer.synced)
+			}
+		}
+		writer.Abort()
+	}
+}
@@ -0,0 +1,83 @@
+package byteblock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// FollowReader reads the blocks of a file that is still being written,
+// like tail -f: once it has read all the complete blocks, it polls the
+// file for new ones until they appear. A block is only returned once
+// it is complete, so that a block being written is never seen half
+// done. Index footers are read as blocks, as ByteBlockReader does.
+type FollowReader struct {
+	file     *os.File
+	reader   *ByteBlockReaderAt
+	pos      int64 // position of the next header
+	interval time.Duration
+}
+
+// NewFollowReader creates a FollowReader of the blocks in f, which
+// checks for new blocks every interval.
+func NewFollowReader(f *os.File, interval time.Duration, opts ...Option) *FollowReader {
+	reader := NewByteBlockReaderAt(f, 0, opts...)
+	return &FollowReader{file: f, reader: reader, pos: reader.opts.preambleSize(), interval: interval}
+}
+
+// Next returns the data of the next block in a newly allocated slice,
+// waiting for it to be written if needed. It returns ctx.Err() if ctx
+// is done first, in which case Next can be called again, and io.EOF
+// after an end marker (see WriteEnd).
+func (r *FollowReader) Next(ctx context.Context) ([]byte, error) {
+	for {
+		data, err := r.next()
+		if err != ErrNotEnoughBytes {
+			return data, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// next reads the next block if it is complete, and returns
+// ErrNotEnoughBytes otherwise.
+func (r *FollowReader) next() ([]byte, error) {
+	info, err := r.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r.reader.size = info.Size()
+	if r.reader.opts.magicHeader && !r.reader.preambleChecked {
+		b := make([]byte, preambleSize)
+		if err := readFullAt(r.file, b, 0); err != nil {
+			return nil, err
+		}
+		if err := r.reader.opts.checkPreamble(b); err != nil {
+			return nil, err
+		}
+		r.reader.preambleChecked = true
+	}
+	if r.pos >= r.reader.size {
+		return nil, ErrNotEnoughBytes
+	}
+	data, next, err := r.reader.ReadBlockAt(r.pos)
+	if err != nil {
+		if errors.Is(err, ErrNotEnoughBytes) {
+			return nil, ErrNotEnoughBytes
+		}
+		return nil, err
+	}
+	r.pos = next
+	return data, nil
+}
+
+// Offset returns the position in the file of the header of the next
+// block.
+func (r *FollowReader) Offset() int64 {
+	return r.pos
+}
@@ -0,0 +1,90 @@
+package byteblock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollowReader(t *testing.T) {
+	opts := []Option{WithMagicHeader(), WithChecksums(), WithBlockKinds()}
+	path := filepath.Join(t.TempDir(), "blocks")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Write the blocks a few bytes at a time, so that the reader sees
+	// incomplete blocks.
+	done := make(chan error)
+	go func() {
+		writer := NewByteBlockWriter(&slowWriter{f}, opts...)
+		for i := 0; i < 10; i++ {
+			writer.WriteString(fmt.Sprint("block ", i), 8)
+		}
+		writer.WriteEnd()
+		done <- writer.Close()
+	}()
+
+	follower := NewFollowReader(r, time.Millisecond, opts...)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		expected := fmt.Sprint("block ", i)
+		if data, err := follower.Next(ctx); string(data) != expected || err != nil {
+			t.Fatalf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	if _, err := follower.Next(ctx); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Waiting for a block that never comes is canceled.
+	f, err = os.Create(filepath.Join(t.TempDir(), "blocks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	NewByteBlockWriter(f, opts...).WriteString("hello", 8)
+	follower = NewFollowReader(f, time.Millisecond, opts...)
+	if data, err := follower.Next(ctx); string(data) != "hello" || err != nil {
+		t.Fatalf("expected %q; got %q, %v", "hello", data, err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := follower.Next(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded; got %v", err)
+	}
+}
+
+// slowWriter writes to w 3 bytes at a time, with a short pause before
+// every write.
+type slowWriter struct {
+	w io.Writer
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		time.Sleep(10 * time.Microsecond)
+		m, err := s.w.Write(p[n:min(n+3, len(p))])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
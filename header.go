@@ -0,0 +1,219 @@
+package byteblock
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// header is a decoded block header.
+type header struct {
+	// length is the number of bytes of the block data, including the
+	// inline name if any.
+	length int64
+	// padding is the number of bytes between the header and the data.
+	padding int64
+	// flags holds the header flags, if enabled.
+	flags uint64
+	// pos is the position of the header in the stream.
+	pos int64
+}
+
+// syncMarker starts every block header with WithSyncMarkers.
+var syncMarker = [8]byte{0xfe, 'b', 'b', 's', 'y', 'n', 'c', 0x01}
+
+// headerSize returns the number of bytes of a block header.
+func (o *options) headerSize() int64 {
+	n := o.syncMarkerSize() + 16
+	if o.hasFlags() {
+		n += 8
+	}
+	return n
+}
+
+// syncMarkerSize returns the number of bytes of the sync marker at the
+// start of a block header.
+func (o *options) syncMarkerSize() int64 {
+	if o.syncMarkers {
+		return int64(len(syncMarker))
+	}
+	return 0
+}
+
+// trailerSize returns the number of bytes that end every block, after
+// any trailing padding.
+func (o *options) trailerSize() int64 {
+	var n int64
+	if o.checksums {
+		n += 8
+	}
+	if o.frameChecksum {
+		n += 8
+	}
+	if o.backLink {
+		n += 8
+	}
+	return n
+}
+
+// blockEnd returns the position right after a block with the given
+// alignment and data length whose header starts at pos, as written by
+// ByteBlockWriter.NewBlock.
+func (o *options) blockEnd(pos, align, length int64) int64 {
+	return o.namedBlockEnd(pos, align, length, "")
+}
+
+// namedBlockEnd is like blockEnd for a block with the given inline
+// name.
+func (o *options) namedBlockEnd(pos, align, length int64, name string) int64 {
+	if o.stride > 0 {
+		return pos + o.stride
+	}
+	nameSize := o.inlineNameSize(name)
+	dataPos := pos + o.headerSize()
+	if o.offsetMode != offsetAlign {
+		dataPos += nameSize
+	}
+	return pos + o.headerSize() + alignOffset(align, dataPos) + nameSize + length + o.trailerSize()
+}
+
+// fieldPos returns the positions of the length and the offset fields
+// within a header.
+func (o *options) fieldPos() (lengthPos, offsetPos int) {
+	base := int(o.syncMarkerSize())
+	if o.offsetFirst {
+		return base + 8, base
+	}
+	return base, base + 8
+}
+
+// readField reads a field of a block header or trailer, in the byte
+// order of WithBigEndian.
+func (o *options) readField(b []byte) int64 {
+	if o.bigEndian {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return readInt64(b)
+}
+
+// fillField is the converse of readField.
+func (o *options) fillField(n int64, b []byte) {
+	if o.bigEndian {
+		binary.BigEndian.PutUint64(b, uint64(n))
+		return
+	}
+	fillInt64(n, b)
+}
+
+// hasFlags tells whether block headers have a flags field, which holds
+// per-block metadata such as the version of NewBlockVer.
+func (o *options) hasFlags() bool {
+	return o.blockVersions || o.blockKinds || o.blockCodecs
+}
+
+// Layout of the flags field.
+const (
+	flagsVersionMask = 0xff
+	flagsKindShift   = 8
+	flagsCodecShift  = 16
+	flagsKeyShift    = 24
+	flagsEncrypted   = 1 << 32
+)
+
+// version returns the version recorded by NewBlockVer.
+func (h header) version() uint8 {
+	return uint8(h.flags & flagsVersionMask)
+}
+
+// kind returns the kind of the block.
+func (h header) kind() BlockKind {
+	return BlockKind(h.flags >> flagsKindShift)
+}
+
+// codec returns the codec the block data is compressed with.
+func (h header) codec() Codec {
+	return Codec(h.flags >> flagsCodecShift)
+}
+
+// encrypted tells whether the block data is sealed, see
+// WithEncryption.
+func (h header) encrypted() bool {
+	return h.flags&flagsEncrypted != 0
+}
+
+// keyID returns the ID of the key the block data is sealed with.
+func (h header) keyID() uint8 {
+	return uint8(h.flags >> flagsKeyShift)
+}
+
+// decodeHeader interprets the block header b found at position pos of
+// the stream. Invalid headers are reported as a *HeaderError.
+func (o *options) decodeHeader(b []byte, pos int64) (h header, err error) {
+	if h, err = o.parseHeader(b, pos); err != nil {
+		return header{}, &HeaderError{Offset: pos, Err: err}
+	}
+	return h, nil
+}
+
+// parseHeader is like decodeHeader except that it returns the bare
+// error.
+func (o *options) parseHeader(b []byte, pos int64) (h header, err error) {
+	if o.syncMarkers && !bytes.Equal(b[:len(syncMarker)], syncMarker[:]) {
+		return header{}, ErrInvalidSyncMarker
+	}
+	h.pos = pos
+	lengthPos, offsetPos := o.fieldPos()
+	h.length = o.readField(b[lengthPos:])
+	if h.length < 0 {
+		return header{}, ErrInvalidLength
+	}
+	if o.maxLength > 0 && h.length > o.maxLength {
+		return header{}, ErrLengthLimit
+	}
+	offset := o.readField(b[offsetPos:])
+	if offset < 0 {
+		return header{}, ErrInvalidOffset
+	}
+	if o.hasFlags() {
+		h.flags = uint64(o.readField(b[o.syncMarkerSize()+16:]))
+	}
+	end := pos + o.headerSize()
+	switch o.offsetMode {
+	case offsetAbsolute:
+		if offset < end {
+			return header{}, ErrInvalidOffset
+		}
+		h.padding = offset - end
+	case offsetAlign:
+		h.padding = alignOffset(offset, end)
+	default:
+		h.padding = offset
+	}
+	if o.maxHeaderPadding > 0 && h.padding > o.maxHeaderPadding {
+		return header{}, ErrPaddingLimit
+	}
+	return h, nil
+}
+
+// HeaderError records an invalid block header, such as one with a
+// negative length or beyond the limits of WithHeaderLimits.
+type HeaderError struct {
+	// Offset is the position of the header in the stream.
+	Offset int64
+	Err    error
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("header at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	ErrLengthLimit       = errors.New("block length exceeds the limit")
+	ErrPaddingLimit      = errors.New("block padding exceeds the limit")
+	ErrInvalidSyncMarker = errors.New("block header does not start with the sync marker")
+)
@@ -0,0 +1,95 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+)
+
+// IndexEntry locates a block in a stream.
+type IndexEntry struct {
+	// Offset is the position of the block header.
+	Offset int64
+	// Length is the length of the block data as stored, including the
+	// inline name if any.
+	Length int64
+}
+
+// indexEntrySize is the number of bytes of an encoded IndexEntry.
+const indexEntrySize = 16
+
+// writeIndexFooter writes the index of the blocks written so far as a
+// block, followed by a footer block of fixed size holding the position
+// of the index block.
+func (w *ByteBlockWriter) writeIndexFooter() error {
+	entries := w.index
+	w.index = nil
+	indexPos := w.numBytesWritten
+	if err := w.newBlock(1, indexEntrySize*int64(len(entries)), blockMeta{raw: true}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		w.fillStub(e.Offset)
+		if err := w.append(w.stub[:]); err != nil {
+			return err
+		}
+		w.fillStub(e.Length)
+		if err := w.append(w.stub[:]); err != nil {
+			return err
+		}
+	}
+	if err := w.newBlock(1, 8, blockMeta{raw: true}); err != nil {
+		return err
+	}
+	w.fillStub(indexPos)
+	return w.append(w.stub[:])
+}
+
+// LoadIndex reads the index written at the end of a stream by a
+// ByteBlockWriter created with WithIndexFooter, from the first size
+// bytes of ra. Only the index and the footer are read.
+func LoadIndex(ra io.ReaderAt, size int64, opts ...Option) ([]IndexEntry, error) {
+	o := newOptions(opts)
+	_, entries, err := o.loadIndex(ra, size)
+	return entries, err
+}
+
+// loadIndex reads the index at the end of a stream of size bytes, and
+// returns it along with the position of the index block, which is
+// where the indexed blocks end.
+func (o *options) loadIndex(ra io.ReaderAt, size int64) (indexPos int64, entries []IndexEntry, err error) {
+	r := &ByteBlockReaderAt{ra: ra, size: size, opts: o.plain()}
+	footerPos := size - o.blockEnd(0, 1, 8)
+	if footerPos < 0 {
+		return 0, nil, ErrInvalidIndex
+	}
+	footer, next, err := r.ReadBlockAt(footerPos)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(footer) != 8 || next != size {
+		return 0, nil, ErrInvalidIndex
+	}
+	indexPos = readInt64(footer)
+	if indexPos < 0 || indexPos >= footerPos {
+		return 0, nil, ErrInvalidIndex
+	}
+	index, next, err := r.ReadBlockAt(indexPos)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(index)%indexEntrySize != 0 || next != footerPos {
+		return 0, nil, ErrInvalidIndex
+	}
+	entries = make([]IndexEntry, len(index)/indexEntrySize)
+	prev := int64(-1)
+	for i := range entries {
+		e := IndexEntry{readInt64(index[i*indexEntrySize:]), readInt64(index[i*indexEntrySize+8:])}
+		if e.Offset <= prev || e.Offset >= indexPos || e.Length < 0 {
+			return 0, nil, ErrInvalidIndex
+		}
+		entries[i], prev = e, e.Offset
+	}
+	return indexPos, entries, nil
+}
+
+var ErrInvalidIndex = errors.New("invalid index footer")
@@ -0,0 +1,76 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestIndexFooter(t *testing.T) {
+	blocks := []string{"hello", "", "wonderful", "world", "!"}
+	for _, opts := range [][]Option{
+		{WithIndexFooter()},
+		{WithIndexFooter(), WithInlineNames(), WithAbsoluteOffset(), WithChecksums()},
+		{WithIndexFooter(), WithCodec(CodecGzip), WithBlockVersions()},
+		{WithIndexFooter(), WithFixedStride(128)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		var offsets []int64
+		for i, b := range blocks {
+			offsets = append(offsets, writer.NextHeaderOffset())
+			writer.WriteString(b, int64(4<<i))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ra := bytes.NewReader(buf.Bytes())
+		entries, err := LoadIndex(ra, int64(buf.Len()), opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []int64
+		for _, e := range entries {
+			got = append(got, e.Offset)
+		}
+		if !reflect.DeepEqual(got, offsets) {
+			t.Errorf("expected offsets %v; got %v", offsets, got)
+		}
+
+		reader := NewByteBlockReaderAt(ra, int64(buf.Len()), opts...)
+		for _, i := range []int{4, 0, 2, 1, 3} {
+			if data, err := reader.ReadBlock(int64(i)); string(data) != blocks[i] || err != nil {
+				t.Errorf("block %d: expected %q; got %q, %v", i, blocks[i], data, err)
+			}
+		}
+		if _, err := reader.ReadBlock(int64(len(blocks))); err != ErrIndexOutOfRange {
+			t.Errorf("expected ErrIndexOutOfRange; got %v", err)
+		}
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		for _, expected := range blocks {
+			if data, err := slicer.Slice(); string(data) != expected || err != nil {
+				t.Errorf("expected %q; got %q, %v", expected, data, err)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+
+		if _, err := LoadIndex(ra, int64(buf.Len())-1, opts...); err == nil {
+			t.Errorf("expected an error for a truncated stream")
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.WriteString("hello", 8)
+	if _, err := LoadIndex(bytes.NewReader(buf.Bytes()), int64(buf.Len()), WithIndexFooter()); err != ErrInvalidIndex {
+		t.Errorf("expected ErrInvalidIndex; got %v", err)
+	}
+	if _, err := NewByteBlockSlicer(buf.Bytes(), WithIndexFooter()).Slice(); err != ErrInvalidIndex {
+		t.Errorf("expected ErrInvalidIndex; got %v", err)
+	}
+}
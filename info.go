@@ -0,0 +1,52 @@
+package byteblock
+
+// BlockInfo describes where a block was found in a stream.
+type BlockInfo struct {
+	// Index is the index of the block in the stream.
+	Index int64
+	// Offset is the position of the block header in the stream.
+	Offset int64
+	// DataOffset is the position of the block data in the stream,
+	// after the inline name if any.
+	DataOffset int64
+	// Length is the number of bytes of the block data, after
+	// decompression with WithCodec.
+	Length int64
+	// Padding is the number of bytes between the header and the data,
+	// or the inline name if any.
+	Padding int64
+}
+
+// SliceInfo is like Slice except that it also describes where the
+// block was found, such as to build an external index.
+func (r *ByteBlockSlicer) SliceInfo() (BlockInfo, []byte, error) {
+	info, _, _, data, err := r.sliceInfo()
+	return info, data, err
+}
+
+// sliceInfo is like SliceInfo except that it also returns the header
+// and the inline name of the block.
+func (r *ByteBlockSlicer) sliceInfo() (info BlockInfo, h header, name, data []byte, err error) {
+	headerPos := r.numBytesSliced
+	index := r.numBlocks
+	if h, name, data, err = r.slice(); err != nil {
+		return BlockInfo{}, header{}, nil, nil, err
+	}
+	info = BlockInfo{
+		Index:      index,
+		Offset:     r.basePos + headerPos,
+		DataOffset: r.basePos + headerPos + r.opts.headerSize() + h.padding,
+		Length:     int64(len(data)),
+		Padding:    h.padding,
+	}
+	if r.opts.inlineNames {
+		info.DataOffset += 2 + int64(len(name))
+	}
+	return info, h, name, data, nil
+}
+
+// ReadInfo is like Read except that it also describes where the block
+// was found.
+func (r *ByteBlockReader) ReadInfo() (BlockInfo, []byte, error) {
+	return r.read()
+}
@@ -0,0 +1,44 @@
+package byteblock
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSliceInfo(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithInlineNames(), WithChecksums()},
+		{WithMagicHeader(), WithAlignInHeader()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		var expected []BlockInfo
+		for i, block := range []string{"hello", "", "aligned"} {
+			offset := writer.NextHeaderOffset()
+			writer.WriteString(block, 64)
+			dataOffset := writer.NextHeaderOffset() - writer.opts.trailerSize() - int64(len(block))
+			expected = append(expected, BlockInfo{
+				Index:      int64(i),
+				Offset:     offset,
+				DataOffset: dataOffset,
+				Length:     int64(len(block)),
+				Padding:    dataOffset - offset - writer.opts.headerSize() - writer.opts.inlineNameSize(""),
+			})
+		}
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		for _, e := range expected {
+			if info, data, err := slicer.SliceInfo(); info != e || int64(len(data)) != e.Length || err != nil {
+				t.Errorf("expected %+v; got %+v, %q, %v", e, info, data, err)
+			}
+			if info, data, err := reader.ReadInfo(); info != e || int64(len(data)) != e.Length || err != nil {
+				t.Errorf("expected %+v; got %+v, %q, %v", e, info, data, err)
+			}
+			if e.DataOffset%64 != 0 {
+				t.Errorf("expected aligned data; got %+v", e)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package byteblock
+
+import "errors"
+
+// MaxInlineNameLength is the maximum length of a name stored by
+// WriteNamedInline.
+const MaxInlineNameLength = 1<<16 - 1
+
+// WriteNamedInline is like Write except that it also stores name at
+// the start of the block data. The writer must have been created with
+// WithInlineNames; otherwise ErrInlineNamesDisabled is returned.
+func (w *ByteBlockWriter) WriteNamedInline(name string, data []byte, align int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.opts.inlineNames {
+		w.err = ErrInlineNamesDisabled
+		return w.err
+	}
+	if w.err = w.newBlock(align, int64(len(data)), blockMeta{name: name}); w.err != nil {
+		return w.err
+	}
+	return w.append(data)
+}
+
+func (w *ByteBlockWriter) writeInlineName(name string) error {
+	w.stub[0] = byte(len(name))
+	w.stub[1] = byte(len(name) >> 8)
+	if w.err = w.rawWrite(w.stub[:2]); w.err != nil {
+		return w.err
+	}
+	w.err = w.rawWrite(stringBytes(name))
+	return w.err
+}
+
+// SliceNamed is like Slice except that it also returns the name
+// stored by WriteNamedInline. The slicer must have been created with
+// WithInlineNames; otherwise ErrInlineNamesDisabled is returned.
+func (r *ByteBlockSlicer) SliceNamed() (name string, data []byte, err error) {
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	if !r.opts.inlineNames {
+		return "", nil, ErrInlineNamesDisabled
+	}
+	_, n, data, err := r.slice()
+	if err != nil {
+		return "", nil, err
+	}
+	return string(n), data, nil
+}
+
+// splitInlineName splits block data into the inline name and the
+// actual data.
+func splitInlineName(data []byte) (name, rest []byte, err error) {
+	if len(data) < 2 {
+		return nil, nil, ErrNotEnoughBytes
+	}
+	nameLength := int(data[0]) | int(data[1])<<8
+	if nameLength > len(data)-2 {
+		return nil, nil, ErrNotEnoughBytes
+	}
+	return data[2 : 2+nameLength], data[2+nameLength:], nil
+}
+
+// GetByName reads the data of the first block with the given inline
+// name into a newly allocated slice. On the first call, the names of
+// all blocks are read to build an index; with WithIndexFooter, the
+// blocks are located through the index footer, and otherwise by
+// reading their headers in turn. The reader must have been created
+// with WithInlineNames; otherwise ErrInlineNamesDisabled is returned.
+// ErrNameNotFound is returned if no block has the name.
+func (r *ByteBlockReaderAt) GetByName(name string) ([]byte, error) {
+	if !r.opts.inlineNames {
+		return nil, ErrInlineNamesDisabled
+	}
+	if r.names == nil {
+		names := map[string]int64{}
+		for i := int64(0); ; i++ {
+			headerPos, err := r.locate(i)
+			if err == ErrIndexOutOfRange {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			n, err := r.nameAt(headerPos)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := names[n]; !ok {
+				names[n] = headerPos
+			}
+		}
+		r.names = names
+	}
+	headerPos, ok := r.names[name]
+	if !ok {
+		return nil, ErrNameNotFound
+	}
+	data, _, err := r.ReadBlockAt(headerPos)
+	return data, err
+}
+
+// nameAt reads the inline name of the block whose header starts at
+// headerPos.
+func (r *ByteBlockReaderAt) nameAt(headerPos int64) (string, error) {
+	length, dataPos, err := r.opts.lengthAt(r.ra, headerPos)
+	if err != nil {
+		return "", err
+	}
+	var b [2]byte
+	if length < 2 {
+		return "", ErrNotEnoughBytes
+	}
+	if err := readFullAt(r.ra, b[:], dataPos); err != nil {
+		return "", err
+	}
+	nameLength := int64(b[0]) | int64(b[1])<<8
+	if nameLength > length-2 {
+		return "", ErrNotEnoughBytes
+	}
+	name := make([]byte, nameLength)
+	if err := readFullAt(r.ra, name, dataPos+2); err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+var (
+	ErrNameNotFound        = errors.New("no block with the name")
+	ErrInlineNamesDisabled = errors.New("inline names are not enabled")
+	ErrNameTooLong         = errors.New("block name is too long")
+)
@@ -0,0 +1,106 @@
+package byteblock
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInlineNames(t *testing.T) {
+	blocks := []struct {
+		Name, Data string
+	}{
+		{"greeting", "hello"}, {"", "world"}, {"empty", ""}, {"last", "!!!!!!!!"},
+	}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithInlineNames())
+	var ends []int
+	for i, b := range blocks {
+		if i == 1 {
+			writer.WriteString(b.Data, 8)
+		} else if err := writer.WriteNamedInline(b.Name, []byte(b.Data), 8); err != nil {
+			t.Fatalf("block %d: unexpected error: %v", i, err)
+		}
+		if start := buf.Len() - len(b.Data); start%8 != 0 {
+			t.Errorf("block %d: misaligned data starting at %d", i, start)
+		}
+		ends = append(ends, buf.Len())
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithInlineNames())
+	for i, b := range blocks {
+		name, data, err := slicer.SliceNamed()
+		if name != b.Name || string(data) != b.Data || err != nil {
+			t.Errorf("block %d: expected %+v; got %q, %q, %v", i, b, name, data, err)
+		}
+	}
+	slicer = NewByteBlockSlicer(buf.Bytes(), WithInlineNames())
+	for i, b := range blocks {
+		if data, err := slicer.Slice(); string(data) != b.Data || err != nil {
+			t.Errorf("block %d: expected %q; got %q, %v", i, b.Data, data, err)
+		}
+	}
+
+	// Blocks before the truncation point keep their names.
+	slicer = NewByteBlockSlicer(buf.Bytes()[:ends[2]+20], WithInlineNames())
+	for i, b := range blocks[:3] {
+		if name, _, err := slicer.SliceNamed(); name != b.Name || err != nil {
+			t.Errorf("truncated block %d: expected %q; got %q, %v", i, b.Name, name, err)
+		}
+	}
+	if _, _, err := slicer.SliceNamed(); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+
+	if err := NewByteBlockWriter(&buf).WriteNamedInline("a", nil, 0); err != ErrInlineNamesDisabled {
+		t.Errorf("expected ErrInlineNamesDisabled; got %v", err)
+	}
+	if _, _, err := NewByteBlockSlicer(buf.Bytes()).SliceNamed(); err != ErrInlineNamesDisabled {
+		t.Errorf("expected ErrInlineNamesDisabled; got %v", err)
+	}
+	long := strings.Repeat("x", MaxInlineNameLength+1)
+	if err := NewByteBlockWriter(&buf, WithInlineNames()).WriteNamedInline(long, nil, 0); err != ErrNameTooLong {
+		t.Errorf("expected ErrNameTooLong; got %v", err)
+	}
+}
+
+func TestGetByName(t *testing.T) {
+	tensors := map[string]string{
+		"weights": "0123456789",
+		"bias":    "abc",
+		"empty":   "",
+	}
+	for _, opts := range [][]Option{
+		{WithInlineNames()},
+		{WithInlineNames(), WithIndexFooter(), WithCodec(CodecFlate)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, name := range []string{"weights", "bias", "empty"} {
+			writer.WriteNamedInline(name, []byte(tensors[name]), 64)
+		}
+		writer.WriteNamedInline("bias", []byte("shadowed"), 64)
+		writer.Write([]byte("unnamed"), 8)
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		reader := NewByteBlockReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), opts...)
+		for _, name := range []string{"bias", "empty", "weights"} {
+			if data, err := reader.GetByName(name); string(data) != tensors[name] || err != nil {
+				t.Errorf("%q: expected %q; got %q, %v", name, tensors[name], data, err)
+			}
+		}
+		if data, err := reader.GetByName(""); string(data) != "unnamed" || err != nil {
+			t.Errorf("expected %q; got %q, %v", "unnamed", data, err)
+		}
+		if _, err := reader.GetByName("missing"); err != ErrNameNotFound {
+			t.Errorf("expected ErrNameNotFound; got %v", err)
+		}
+	}
+
+	reader := NewByteBlockReaderAt(bytes.NewReader(nil), 0)
+	if _, err := reader.GetByName("weights"); err != ErrInlineNamesDisabled {
+		t.Errorf("expected ErrInlineNamesDisabled; got %v", err)
+	}
+}
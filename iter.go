@@ -0,0 +1,69 @@
+package byteblock
+
+import (
+	"io"
+	"iter"
+)
+
+// All returns an iterator over the remaining blocks, as returned by
+// Slice, for use in a range loop:
+//
+//	for data, err := range slicer.All() {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The iteration ends after the last block, or after yielding the
+// first error other than io.EOF.
+func (r *ByteBlockSlicer) All() iter.Seq2[[]byte, error] {
+	return allBlocks(r.Slice)
+}
+
+// All returns an iterator over the remaining blocks, as returned by
+// Read. It ends like ByteBlockSlicer.All.
+func (r *ByteBlockReader) All() iter.Seq2[[]byte, error] {
+	return allBlocks(r.Read)
+}
+
+// Block is a block along with where it was found, as yielded by
+// AllInfo.
+type Block struct {
+	BlockInfo
+	Data []byte
+}
+
+// AllInfo is like All except that it also describes where each block
+// was found, as SliceInfo does.
+func (r *ByteBlockSlicer) AllInfo() iter.Seq2[Block, error] {
+	return allBlocks(func() (Block, error) {
+		info, data, err := r.SliceInfo()
+		return Block{info, data}, err
+	})
+}
+
+// AllInfo is like All except that it also describes where each block
+// was found, as ReadInfo does.
+func (r *ByteBlockReader) AllInfo() iter.Seq2[Block, error] {
+	return allBlocks(func() (Block, error) {
+		info, data, err := r.ReadInfo()
+		return Block{info, data}, err
+	})
+}
+
+// allBlocks returns an iterator over the blocks returned by next until
+// io.EOF or another error, which is yielded.
+func allBlocks[T any](next func() (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			data, err := next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(data, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package byteblock
+
+import (
+	"bytes"
+	"iter"
+	"reflect"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, block := range []string{"a", "bc", "", "def"} {
+		writer.WriteString(block, 8)
+	}
+	expected := []string{"a", "bc", "", "def"}
+
+	var got []string
+	for data, err := range NewByteBlockSlicer(buf.Bytes()).All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, string(data))
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+
+	got = nil
+	for data, err := range NewByteBlockReader(bytes.NewReader(buf.Bytes())).All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, string(data))
+		if len(got) == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(got, expected[:2]) {
+		t.Errorf("expected %q; got %q", expected[:2], got)
+	}
+
+	// The first error ends the iteration.
+	var errs []error
+	for _, err := range NewByteBlockSlicer(buf.Bytes()[:buf.Len()-1]).All() {
+		errs = append(errs, err)
+	}
+	if !reflect.DeepEqual(errs, []error{nil, nil, nil, ErrNotEnoughBytes}) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestAllInfo(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	var offsets []int64
+	for _, block := range []string{"a", "bc", "", "def"} {
+		offsets = append(offsets, writer.NextHeaderOffset())
+		writer.WriteString(block, 8)
+	}
+	expected := []string{"a", "bc", "", "def"}
+
+	for _, all := range []iter.Seq2[Block, error]{
+		NewByteBlockSlicer(buf.Bytes()).AllInfo(),
+		NewByteBlockReader(bytes.NewReader(buf.Bytes())).AllInfo(),
+	} {
+		var got []string
+		for block, err := range all {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			i := len(got)
+			if block.Index != int64(i) || block.Offset != offsets[i] || block.Length != int64(len(block.Data)) {
+				t.Errorf("unexpected info for block %d: %+v", i, block.BlockInfo)
+			}
+			got = append(got, string(block.Data))
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+
+	// The first error ends the iteration.
+	var errs []error
+	for _, err := range NewByteBlockReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1])).AllInfo() {
+		errs = append(errs, err)
+	}
+	if len(errs) != 4 || errs[2] != nil || errs[3] == nil {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
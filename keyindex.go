@@ -0,0 +1,61 @@
+package byteblock
+
+import (
+	"bytes"
+	"sort"
+)
+
+// KeySize is the length of the keys of a key index.
+const KeySize = 16
+
+// keyEntrySize is the number of bytes of an entry of a key index.
+const keyEntrySize = KeySize + 8
+
+// KeyEntry maps a key to the position of a block, as stored in a key
+// index.
+type KeyEntry struct {
+	Key    [KeySize]byte
+	Offset int64
+}
+
+// WriteKeyIndex writes entries as one block with the given alignment,
+// sorted by key, so that LookupKey can binary-search the block data
+// without decoding it. Each entry takes 24 bytes: the key followed by
+// the offset as an int64. entries itself is not modified.
+func (w *ByteBlockWriter) WriteKeyIndex(entries []KeyEntry, align int64) error {
+	sorted := append([]KeyEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Key[:], sorted[j].Key[:]) < 0
+	})
+	if err := w.NewBlock(align, keyEntrySize*int64(len(sorted))); err != nil {
+		return err
+	}
+	for i := range sorted {
+		if err := w.append(sorted[i].Key[:]); err != nil {
+			return err
+		}
+		w.fillStub(sorted[i].Offset)
+		if err := w.append(w.stub[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LookupKey binary-searches indexBlock, the data of a block written by
+// WriteKeyIndex, for key and returns its offset. If key was given
+// several times, the first of them in the original entries is found.
+func LookupKey(indexBlock []byte, key [KeySize]byte) (offset int64, found bool) {
+	n := len(indexBlock) / keyEntrySize
+	i := sort.Search(n, func(i int) bool {
+		return bytes.Compare(indexBlock[i*keyEntrySize:i*keyEntrySize+KeySize], key[:]) >= 0
+	})
+	if i == n {
+		return 0, false
+	}
+	entry := indexBlock[i*keyEntrySize:]
+	if !bytes.Equal(entry[:KeySize], key[:]) {
+		return 0, false
+	}
+	return readInt64(entry[KeySize:]), true
+}
@@ -0,0 +1,61 @@
+package byteblock
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyIndex(t *testing.T) {
+	key := func(s string) (k [KeySize]byte) {
+		copy(k[:], s)
+		return k
+	}
+	entries := []KeyEntry{
+		{key("delta"), 400},
+		{key("alpha"), 100},
+		{key("charlie"), 300},
+		{key("bravo"), 200},
+		{key("alpha"), 999},
+	}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	if err := writer.WriteKeyIndex(entries, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries[0].Key != key("delta") {
+		t.Errorf("expected entries to be left unsorted")
+	}
+	index, err := NewByteBlockSlicer(buf.Bytes()).Slice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(index) != len(entries)*keyEntrySize {
+		t.Fatalf("expected %d bytes; got %d", len(entries)*keyEntrySize, len(index))
+	}
+	for i := keyEntrySize; i < len(index); i += keyEntrySize {
+		if bytes.Compare(index[i-keyEntrySize:i-keyEntrySize+KeySize], index[i:i+KeySize]) > 0 {
+			t.Errorf("entry %d is out of order", i/keyEntrySize)
+		}
+	}
+
+	for _, i := range []struct {
+		Key    string
+		Offset int64
+		Found  bool
+	}{
+		{"alpha", 100, true},
+		{"bravo", 200, true},
+		{"charlie", 300, true},
+		{"delta", 400, true},
+		{"", 0, false},
+		{"alphabet", 0, false},
+		{"echo", 0, false},
+	} {
+		if offset, found := LookupKey(index, key(i.Key)); offset != i.Offset || found != i.Found {
+			t.Errorf("%q: expected %d, %v; got %d, %v", i.Key, i.Offset, i.Found, offset, found)
+		}
+	}
+	if _, found := LookupKey(nil, key("alpha")); found {
+		t.Errorf("expected nothing in an empty index")
+	}
+}
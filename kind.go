@@ -0,0 +1,76 @@
+package byteblock
+
+import "errors"
+
+// BlockKind tells what a block holds. It is recorded in block headers
+// when enabled by WithBlockKinds.
+type BlockKind uint8
+
+const (
+	// KindData is the kind of ordinary blocks.
+	KindData BlockKind = iota
+	// KindParity is the kind of blocks written by WriteParity.
+	KindParity
+	// KindCoalesced is the kind of blocks written by Coalesce.
+	KindCoalesced
+	// KindEnd is the kind of the empty block written by WriteEnd.
+	KindEnd
+)
+
+func (k BlockKind) String() string {
+	switch k {
+	case KindData:
+		return "data"
+	case KindParity:
+		return "parity"
+	case KindCoalesced:
+		return "coalesced"
+	case KindEnd:
+		return "end"
+	}
+	return "unknown"
+}
+
+// writeKind writes data as a block of the given kind.
+func (w *ByteBlockWriter) writeKind(kind BlockKind, data []byte, align int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.opts.blockKinds {
+		w.err = ErrBlockKindsDisabled
+		return w.err
+	}
+	if w.err = w.newBlock(align, int64(len(data)), blockMeta{flags: uint64(kind) << flagsKindShift}); w.err != nil {
+		return w.err
+	}
+	return w.append(data)
+}
+
+// WriteEnd writes an empty block of KindEnd, which marks the end of
+// the stream: slicers and readers created with WithBlockKinds return
+// io.EOF on reaching it, whatever follows. This allows embedding a
+// stream in a container with other data after it. The writer must have
+// been created with WithBlockKinds; otherwise ErrBlockKindsDisabled is
+// returned.
+func (w *ByteBlockWriter) WriteEnd() error {
+	return w.writeKind(KindEnd, nil, 0)
+}
+
+// SliceKind is like Slice except that it also returns the kind of the
+// block. The slicer must have been created with WithBlockKinds;
+// otherwise ErrBlockKindsDisabled is returned.
+func (r *ByteBlockSlicer) SliceKind() ([]byte, BlockKind, error) {
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	if !r.opts.blockKinds {
+		return nil, 0, ErrBlockKindsDisabled
+	}
+	h, _, data, err := r.slice()
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, h.kind(), nil
+}
+
+var ErrBlockKindsDisabled = errors.New("block kinds are not enabled")
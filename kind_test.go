@@ -0,0 +1,46 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestWriteEnd(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithBlockKinds())
+	writer.WriteString("hello", 8)
+	writer.WriteString("world", 8)
+	if err := writer.WriteEnd(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	end := buf.Len()
+	buf.WriteString("trailing garbage that is not a block")
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithBlockKinds())
+	for _, expected := range []string{"hello", "world"} {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+	}
+	if slicer.numBytesSliced != int64(end) {
+		t.Errorf("expected to stop at %d; got %d", end, slicer.numBytesSliced)
+	}
+
+	reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), WithBlockKinds())
+	if n, err := reader.Drain(); n != 2 || err != nil {
+		t.Errorf("expected 2 blocks; got %d, %v", n, err)
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	if err := NewByteBlockWriter(&buf).WriteEnd(); err != ErrBlockKindsDisabled {
+		t.Errorf("expected ErrBlockKindsDisabled; got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package byteblock
+
+import (
+	"crypto/sha256"
+	"errors"
+)
+
+// Layout plans where the blocks written by a ByteBlockWriter with the
+// same options will land, without writing anything. Declaring the
+// alignment and the length of every block upfront gives their exact
+// positions and the size of the whole stream, so that a file can be
+// preallocated and blocks can refer to blocks written after them.
+type Layout struct {
+	pos    int64
+	blocks []BlockInfo
+	opts   options
+}
+
+// NewLayout creates an empty layout for streams written with opts.
+func NewLayout(opts ...Option) *Layout {
+	o := newOptions(opts)
+	pos := o.startOffset
+	if pos == 0 {
+		pos = o.preambleSize()
+	}
+	return &Layout{pos: pos, opts: o}
+}
+
+// Add declares the next block, as passed to ByteBlockWriter.Write, and
+// returns where it will be written. With WithFixedStride,
+// ErrBlockTooLarge is returned if the block does not fit. As the
+// lengths of compressed blocks are not known in advance, blocks cannot
+// be planned with WithCodec, and ErrLayoutUnsupported is returned.
+// With WithEncryption, length is that of the data before sealing.
+func (l *Layout) Add(align, length int64) (BlockInfo, error) {
+	o := &l.opts
+	if o.codec != CodecNone {
+		return BlockInfo{}, ErrLayoutUnsupported
+	}
+	if o.keys != nil && length > 0 {
+		length += sealOverhead
+	}
+	nameSize := o.inlineNameSize("")
+	dataPos := l.pos + o.headerSize()
+	if o.offsetMode != offsetAlign {
+		dataPos += nameSize
+	}
+	padding := alignOffset(align, dataPos)
+	if o.stride > 0 && o.headerSize()+padding+nameSize > o.stride-length-o.trailerSize() {
+		return BlockInfo{}, ErrBlockTooLarge
+	}
+	info := BlockInfo{
+		Index:      int64(len(l.blocks)),
+		Offset:     l.pos,
+		DataOffset: l.pos + o.headerSize() + padding + nameSize,
+		Length:     length,
+		Padding:    padding,
+	}
+	l.blocks = append(l.blocks, info)
+	l.pos = o.blockEnd(l.pos, align, length)
+	return info, nil
+}
+
+// Blocks returns the blocks declared so far.
+func (l *Layout) Blocks() []BlockInfo {
+	return l.blocks
+}
+
+// Size returns the size of the stream once the declared blocks are
+// written and the writer is closed, including the index footer of
+// WithIndexFooter and the trailer of WithStreamMAC.
+func (l *Layout) Size() int64 {
+	size := l.pos
+	if l.opts.indexFooter {
+		size = l.opts.blockEnd(size, 1, indexEntrySize*int64(len(l.blocks)))
+		size = l.opts.blockEnd(size, 1, 8)
+	}
+	if l.opts.macKey != nil {
+		size = l.opts.blockEnd(size, 1, sha256.Size)
+	}
+	return size
+}
+
+var ErrLayoutUnsupported = errors.New("blocks cannot be planned with the options")
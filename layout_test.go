@@ -0,0 +1,58 @@
+package byteblock
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	blocks := []struct {
+		Align, Length int64
+	}{{16, 5}, {1, 0}, {4096, 100}, {8, 3}}
+	for _, opts := range [][]Option{
+		nil,
+		{WithChecksums(), WithBackLink(), WithInlineNames()},
+		{WithAlignInHeader(), WithInlineNames(), WithIndexFooter()},
+		{WithMagicHeader(), WithSyncMarkers(), WithBlockKinds()},
+		{WithFixedStride(8192), WithFrameChecksum()},
+		{WithStartOffset(10)},
+	} {
+		layout := NewLayout(opts...)
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, b := range blocks {
+			offset := writer.NextHeaderOffset()
+			info, err := layout.Add(b.Align, b.Length)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if info.Offset != offset {
+				t.Errorf("expected header offset %d; got %d", offset, info.Offset)
+			}
+			writer.Write(make([]byte, b.Length), b.Align)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		start := newOptions(opts).startOffset
+		if size := layout.Size(); size != start+int64(buf.Len()) {
+			t.Errorf("expected size %d; got %d", start+int64(buf.Len()), size)
+		}
+		if start > 0 {
+			continue
+		}
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		for _, info := range layout.Blocks() {
+			if expected, _, err := slicer.SliceInfo(); info != expected || err != nil {
+				t.Errorf("expected %+v; got %+v, %v", expected, info, err)
+			}
+		}
+	}
+
+	if _, err := NewLayout(WithFixedStride(64)).Add(8, 64); err != ErrBlockTooLarge {
+		t.Errorf("expected ErrBlockTooLarge; got %v", err)
+	}
+	if _, err := NewLayout(WithCodec(CodecGzip)).Add(8, 64); err != ErrLayoutUnsupported {
+		t.Errorf("expected ErrLayoutUnsupported; got %v", err)
+	}
+}
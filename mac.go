@@ -0,0 +1,134 @@
+package byteblock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+)
+
+// macTrailerSize returns the number of bytes of the trailer block of
+// WithStreamMAC.
+func (o *options) macTrailerSize() int64 {
+	return o.blockEnd(0, 1, sha256.Size)
+}
+
+// writeMAC writes the MAC of the stream written so far as the trailer
+// block, which itself is not covered.
+func (w *ByteBlockWriter) writeMAC() error {
+	sum := w.mac.Sum(nil)
+	w.mac = nil
+	if err := w.newBlock(1, int64(len(sum)), blockMeta{raw: true}); err != nil {
+		return err
+	}
+	return w.append(sum)
+}
+
+// checkMAC verifies the MAC in the trailer at the end of a stream of
+// size bytes, and returns the position of the trailer, which is where
+// the rest of the stream ends.
+func (o *options) checkMAC(ra io.ReaderAt, size int64) (macPos int64, err error) {
+	macPos = size - o.macTrailerSize()
+	if macPos < 0 {
+		return 0, ErrInvalidStreamMAC
+	}
+	r := &ByteBlockReaderAt{ra: ra, size: size, opts: o.plain()}
+	sum, next, err := r.ReadBlockAt(macPos)
+	if err != nil {
+		return 0, err
+	}
+	if len(sum) != sha256.Size || next != size {
+		return 0, ErrInvalidStreamMAC
+	}
+	mac := hmac.New(sha256.New, o.macKey)
+	if _, err := io.Copy(mac, io.NewSectionReader(ra, 0, macPos)); err != nil {
+		return 0, err
+	}
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return 0, ErrStreamMACMismatch
+	}
+	return macPos, nil
+}
+
+// macReader reads a stream of WithStreamMAC for a ByteBlockReader. It
+// computes the MAC of the bytes it returns, while withholding as many
+// bytes as the trailer takes, so that the stream appears to end before
+// the trailer. Once the underlying reader ends, the withheld bytes are
+// checked to be a trailer holding the MAC, and io.EOF is only returned
+// if they are.
+type macReader struct {
+	r       io.Reader
+	mac     hash.Hash
+	pos     int64  // number of bytes returned
+	buf     []byte // bytes read but not returned; nil once verified
+	trailer int    // number of bytes withheld
+	err     error
+	opts    *options
+}
+
+func newMACReader(r io.Reader, o *options) *macReader {
+	trailer := int(o.macTrailerSize())
+	return &macReader{
+		r:       r,
+		mac:     hmac.New(sha256.New, o.macKey),
+		buf:     make([]byte, 0, trailer+32<<10),
+		trailer: trailer,
+		opts:    o,
+	}
+}
+
+func (m *macReader) Read(p []byte) (int, error) {
+	for len(m.buf) <= m.trailer && m.err == nil {
+		var n int
+		n, m.err = m.r.Read(m.buf[len(m.buf):cap(m.buf)])
+		m.buf = m.buf[:len(m.buf)+n]
+	}
+	if len(m.buf) > m.trailer {
+		n := copy(p, m.buf[:len(m.buf)-m.trailer])
+		m.mac.Write(p[:n])
+		m.pos += int64(n)
+		m.buf = m.buf[:copy(m.buf, m.buf[n:])]
+		return n, nil
+	}
+	if m.err == io.EOF && m.buf != nil {
+		if err := m.verify(); err != nil {
+			m.err = err
+		}
+		m.buf = nil
+	}
+	return 0, m.err
+}
+
+// verify checks that the withheld bytes are a trailer holding the MAC
+// of the bytes returned.
+func (m *macReader) verify() error {
+	trailer := &ByteBlockSlicer{data: m.buf, basePos: m.pos, prevLength: -1, blocksLeft: -1, opts: m.opts.plain()}
+	sum, err := trailer.Slice()
+	if err != nil || len(sum) != sha256.Size || trailer.numBytesSliced != int64(len(m.buf)) {
+		return ErrInvalidStreamMAC
+	}
+	if !hmac.Equal(m.mac.Sum(nil), sum) {
+		return ErrStreamMACMismatch
+	}
+	return nil
+}
+
+// VerifyMAC verifies the MAC of WithStreamMAC at the end of the first
+// size bytes of ra, reading the whole stream once. It returns
+// ErrStreamMACMismatch if the stream was altered or the key differs.
+func VerifyMAC(ra io.ReaderAt, size int64, opts ...Option) error {
+	o := newOptions(opts)
+	if o.macKey == nil {
+		return ErrNoStreamMAC
+	}
+	_, err := o.checkMAC(ra, size)
+	return err
+}
+
+var (
+	ErrStreamMACMismatch = errors.New("stream MAC does not match")
+	ErrInvalidStreamMAC  = errors.New("invalid stream MAC trailer")
+	ErrNoStreamMAC       = errors.New("stream MAC is not enabled")
+	ErrAppendStreamMAC   = errors.New("cannot append to a stream with a MAC")
+)
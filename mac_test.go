@@ -0,0 +1,184 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamMAC(t *testing.T) {
+	key := []byte("secret key")
+	for _, opts := range [][]Option{
+		{WithStreamMAC(key)},
+		{WithStreamMAC(key), WithMagicHeader(), WithIndexFooter(), WithChecksums()},
+		{WithStreamMAC(key), WithFixedStride(64)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		writer.WriteString("hello", 8)
+		// Rolled back blocks are not covered.
+		writer.Begin()
+		writer.WriteString("dropped", 8)
+		writer.Rollback()
+		writer.Begin()
+		writer.WriteString("kept", 8)
+		writer.Commit()
+		writer.NewBlock(16, 5)
+		writer.ReadFrom(strings.NewReader("world"))
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data := buf.Bytes()
+
+		expected := []string{"hello", "kept", "world"}
+		slicer := NewByteBlockSlicer(data, opts...)
+		for _, e := range expected {
+			if block, err := slicer.Slice(); string(block) != e || err != nil {
+				t.Errorf("expected %q; got %q, %v", e, block, err)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+		readerAt := NewByteBlockReaderAt(bytes.NewReader(data), int64(len(data)), opts...)
+		for i, e := range expected {
+			if block, err := readerAt.ReadBlock(int64(i)); string(block) != e || err != nil {
+				t.Errorf("expected %q; got %q, %v", e, block, err)
+			}
+		}
+		if _, err := readerAt.ReadBlock(int64(len(expected))); err != ErrIndexOutOfRange {
+			t.Errorf("expected ErrIndexOutOfRange; got %v", err)
+		}
+		if err := VerifyMAC(bytes.NewReader(data), int64(len(data)), opts...); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		// Any change is caught.
+		tampered := bytes.Clone(data)
+		tampered[len(tampered)/2] ^= 1
+		if err := VerifyMAC(bytes.NewReader(tampered), int64(len(tampered)), opts...); err != ErrStreamMACMismatch {
+			t.Errorf("expected ErrStreamMACMismatch; got %v", err)
+		}
+		if _, err := NewByteBlockSlicer(tampered, opts...).Slice(); err != ErrStreamMACMismatch {
+			t.Errorf("expected ErrStreamMACMismatch; got %v", err)
+		}
+		other := append(opts[1:], WithStreamMAC([]byte("other key")))
+		if err := VerifyMAC(bytes.NewReader(data), int64(len(data)), other...); err != ErrStreamMACMismatch {
+			t.Errorf("expected ErrStreamMACMismatch; got %v", err)
+		}
+	}
+
+	if err := VerifyMAC(bytes.NewReader(nil), 0); err != ErrNoStreamMAC {
+		t.Errorf("expected ErrNoStreamMAC; got %v", err)
+	}
+	if err := VerifyMAC(bytes.NewReader(nil), 0, WithStreamMAC(key)); err != ErrInvalidStreamMAC {
+		t.Errorf("expected ErrInvalidStreamMAC; got %v", err)
+	}
+	if err := NewByteBlockWriter(io.Discard, WithStreamMAC(key), WithStartOffset(8)).WriteString("x", 8); err != ErrAppendStreamMAC {
+		t.Errorf("expected ErrAppendStreamMAC; got %v", err)
+	}
+	if _, err := NewByteBlockWriter(io.Discard, WithStreamMAC(key)).ReserveBlock(8, 4); err != ErrReserveUnsupported {
+		t.Errorf("expected ErrReserveUnsupported; got %v", err)
+	}
+}
+
+func TestStreamMACReader(t *testing.T) {
+	key := []byte("secret key")
+	for _, opts := range [][]Option{
+		{WithStreamMAC(key)},
+		{WithStreamMAC(key), WithMagicHeader(), WithFixedStride(64)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		layout := NewLayout(opts...)
+		expected := []string{"hello", "", "world"}
+		for _, e := range expected {
+			writer.WriteString(e, 8)
+			layout.Add(8, int64(len(e)))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data := buf.Bytes()
+		if size := layout.Size(); size != int64(len(data)) {
+			t.Errorf("expected size %d; got %d", len(data), size)
+		}
+
+		// The trailer is hidden, and the MAC verified at the end.
+		reader := NewByteBlockReader(bytes.NewReader(data), opts...)
+		for _, e := range expected {
+			if block, err := reader.Read(); string(block) != e || err != nil {
+				t.Errorf("expected %q; got %q, %v", e, block, err)
+			}
+		}
+		if _, err := reader.Read(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+		if n, err := Validate(bytes.NewReader(data), opts...); n != int64(len(expected)) || err != nil {
+			t.Errorf("expected %d blocks; got %d, %v", len(expected), n, err)
+		}
+
+		tampered := bytes.Clone(data)
+		tampered[bytes.Index(tampered, []byte("world"))] ^= 1
+		reader = NewByteBlockReader(bytes.NewReader(tampered), opts...)
+		if n, err := reader.Drain(); n != len(expected) || err != ErrStreamMACMismatch {
+			t.Errorf("expected %d blocks, ErrStreamMACMismatch; got %d, %v", len(expected), n, err)
+		}
+		if _, err := Validate(bytes.NewReader(tampered), opts...); !errors.Is(err, ErrStreamMACMismatch) {
+			t.Errorf("expected ErrStreamMACMismatch; got %v", err)
+		}
+		o := newOptions(opts)
+		truncated := data[:len(data)-int(o.macTrailerSize())]
+		if _, err := Validate(bytes.NewReader(truncated), opts...); !errors.Is(err, ErrInvalidStreamMAC) {
+			t.Errorf("expected ErrInvalidStreamMAC; got %v", err)
+		}
+
+		// Merge verifies the MAC of each stream, and writes its own.
+		var merged bytes.Buffer
+		if n, err := Concat(&merged, []io.Reader{bytes.NewReader(data), bytes.NewReader(data)}, opts...); n != 2*int64(len(expected)) || err != nil {
+			t.Fatalf("expected %d blocks; got %d, %v", 2*len(expected), n, err)
+		}
+		if err := VerifyMAC(bytes.NewReader(merged.Bytes()), int64(merged.Len()), opts...); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if _, err := Concat(io.Discard, []io.Reader{bytes.NewReader(tampered)}, opts...); err != ErrStreamMACMismatch {
+			t.Errorf("expected ErrStreamMACMismatch; got %v", err)
+		}
+
+		// So does Split, and every segment has a MAC that fits.
+		var segments []*bytes.Buffer
+		create := func(int) (io.WriteCloser, error) {
+			segments = append(segments, &bytes.Buffer{})
+			return bufferCloser{segments[len(segments)-1]}, nil
+		}
+		// A segment fits the blocks of one source stream, trailer included.
+		maxSize := int64(len(data))
+		if _, err := Split(bytes.NewReader(merged.Bytes()), maxSize, create, opts...); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(segments) < 2 {
+			t.Errorf("expected several segments; got %d", len(segments))
+		}
+		for _, s := range segments {
+			if int64(s.Len()) > maxSize {
+				t.Errorf("expected at most %d bytes; got %d", maxSize, s.Len())
+			}
+			if err := VerifyMAC(bytes.NewReader(s.Bytes()), int64(s.Len()), opts...); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+		segments = nil
+		if _, err := Split(bytes.NewReader(tampered), 1<<20, create, opts...); err != ErrStreamMACMismatch {
+			t.Errorf("expected ErrStreamMACMismatch; got %v", err)
+		}
+	}
+}
+
+// bufferCloser is a bytes.Buffer with a no-op Close.
+type bufferCloser struct {
+	*bytes.Buffer
+}
+
+func (bufferCloser) Close() error { return nil }
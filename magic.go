@@ -0,0 +1,223 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// FormatVersion is the version of the stream format written in the
+// preamble, see WithMagicHeader.
+const FormatVersion = 1
+
+// magic starts the preamble of a stream written with WithMagicHeader.
+// It is followed by the format version, then by an int64 of format
+// flags, which keeps the blocks 8-byte aligned.
+var magic = [7]byte{'B', 'Y', 'T', 'E', 'B', 'L', 'K'}
+
+// preambleSize is the number of bytes of the preamble.
+const preambleSize = 16
+
+// countHeaderSize is the number of bytes of the count header of
+// WithCountHeader.
+const countHeaderSize = 8
+
+// Format flags recorded in the preamble.
+const (
+	formatHeaderFlags = 1 << iota
+	formatAbsoluteOffset
+	formatAlignInHeader
+	formatOffsetFirst
+	formatInlineNames
+	formatChecksums
+	formatFrameChecksum
+	formatBackLink
+	formatIndexFooter
+	formatFixedStride
+	formatSyncMarkers
+	formatEncrypted
+	formatStreamMAC
+	formatCountHeader
+	formatBigEndian
+
+	formatCodecShift = 16
+)
+
+// Format describes how a stream is laid out, as recorded in its
+// preamble by WithMagicHeader. The integers of the preamble itself are
+// always little-endian.
+type Format struct {
+	Version uint8
+	// HeaderSize is the number of bytes of each block header, which
+	// includes a flags field with WithBlockVersions, WithBlockKinds or
+	// WithBlockCodecs, and a sync marker with WithSyncMarkers.
+	HeaderSize     int64
+	AbsoluteOffset bool
+	AlignInHeader  bool
+	OffsetFirst    bool
+	InlineNames    bool
+	Checksums      bool
+	FrameChecksum  bool
+	BackLink       bool
+	IndexFooter    bool
+	// FixedStride tells whether blocks have a fixed stride; the
+	// stride itself is not recorded.
+	FixedStride bool
+	Codec       Codec
+	SyncMarkers bool
+	// Encrypted tells whether block data is sealed with
+	// WithEncryption; the keys are not recorded.
+	Encrypted bool
+	// StreamMAC tells whether the stream ends with the trailer of
+	// WithStreamMAC.
+	StreamMAC bool
+	// CountHeader tells whether the preamble is followed by the count
+	// header of WithCountHeader.
+	CountHeader bool
+	// BigEndian tells whether the fields of block headers and
+	// trailers are big-endian, see WithBigEndian.
+	BigEndian bool
+}
+
+// format returns the format of streams written with o.
+func (o *options) format() Format {
+	return Format{
+		Version:        FormatVersion,
+		HeaderSize:     o.headerSize(),
+		AbsoluteOffset: o.offsetMode == offsetAbsolute,
+		AlignInHeader:  o.offsetMode == offsetAlign,
+		OffsetFirst:    o.offsetFirst,
+		InlineNames:    o.inlineNames,
+		Checksums:      o.checksums,
+		FrameChecksum:  o.frameChecksum,
+		BackLink:       o.backLink,
+		IndexFooter:    o.indexFooter,
+		FixedStride:    o.stride > 0,
+		Codec:          o.codec,
+		SyncMarkers:    o.syncMarkers,
+		Encrypted:      o.keys != nil,
+		StreamMAC:      o.macKey != nil,
+		CountHeader:    o.countHeader,
+		BigEndian:      o.bigEndian,
+	}
+}
+
+// preambleSize returns the number of bytes before the first block,
+// which include the count header of WithCountHeader.
+func (o *options) preambleSize() int64 {
+	var n int64
+	if o.magicHeader {
+		n += preambleSize
+	}
+	if o.countHeader {
+		n += countHeaderSize
+	}
+	return n
+}
+
+// encodePreamble returns the preamble of a stream with format f.
+func encodePreamble(f Format) []byte {
+	b := make([]byte, preambleSize)
+	copy(b, magic[:])
+	b[len(magic)] = f.Version
+	headerSize := f.HeaderSize
+	if f.SyncMarkers {
+		headerSize -= int64(len(syncMarker))
+	}
+	var flags int64
+	for _, bit := range []struct {
+		set  bool
+		flag int64
+	}{
+		{headerSize > 16, formatHeaderFlags},
+		{f.AbsoluteOffset, formatAbsoluteOffset},
+		{f.AlignInHeader, formatAlignInHeader},
+		{f.OffsetFirst, formatOffsetFirst},
+		{f.InlineNames, formatInlineNames},
+		{f.Checksums, formatChecksums},
+		{f.FrameChecksum, formatFrameChecksum},
+		{f.BackLink, formatBackLink},
+		{f.IndexFooter, formatIndexFooter},
+		{f.FixedStride, formatFixedStride},
+		{f.SyncMarkers, formatSyncMarkers},
+		{f.Encrypted, formatEncrypted},
+		{f.StreamMAC, formatStreamMAC},
+		{f.CountHeader, formatCountHeader},
+		{f.BigEndian, formatBigEndian},
+	} {
+		if bit.set {
+			flags |= bit.flag
+		}
+	}
+	flags |= int64(f.Codec) << formatCodecShift
+	fillInt64(flags, b[8:])
+	return b
+}
+
+// decodePreamble decodes the preamble at the start of b.
+func decodePreamble(b []byte) (Format, error) {
+	if len(b) < preambleSize || !bytes.Equal(b[:len(magic)], magic[:]) {
+		return Format{}, ErrBadMagic
+	}
+	f := Format{Version: b[len(magic)], HeaderSize: 16}
+	if f.Version == 0 || f.Version > FormatVersion {
+		return Format{}, ErrUnsupportedVersion
+	}
+	flags := readInt64(b[8:])
+	if flags&formatHeaderFlags != 0 {
+		f.HeaderSize = 24
+	}
+	f.AbsoluteOffset = flags&formatAbsoluteOffset != 0
+	f.AlignInHeader = flags&formatAlignInHeader != 0
+	f.OffsetFirst = flags&formatOffsetFirst != 0
+	f.InlineNames = flags&formatInlineNames != 0
+	f.Checksums = flags&formatChecksums != 0
+	f.FrameChecksum = flags&formatFrameChecksum != 0
+	f.BackLink = flags&formatBackLink != 0
+	f.IndexFooter = flags&formatIndexFooter != 0
+	f.FixedStride = flags&formatFixedStride != 0
+	f.SyncMarkers = flags&formatSyncMarkers != 0
+	f.Encrypted = flags&formatEncrypted != 0
+	f.StreamMAC = flags&formatStreamMAC != 0
+	f.CountHeader = flags&formatCountHeader != 0
+	f.BigEndian = flags&formatBigEndian != 0
+	if f.SyncMarkers {
+		f.HeaderSize += int64(len(syncMarker))
+	}
+	f.Codec = Codec(flags >> formatCodecShift)
+	return f, nil
+}
+
+// checkPreamble checks that the preamble at the start of b describes
+// streams written with o.
+func (o *options) checkPreamble(b []byte) error {
+	f, err := decodePreamble(b)
+	if err != nil {
+		return err
+	}
+	if f != o.format() {
+		return ErrFormatMismatch
+	}
+	return nil
+}
+
+// Probe reads only the preamble at the start of r, as written with
+// WithMagicHeader, and returns the format it records. This tells
+// cheaply whether a file holds blocks and which options read it.
+// ErrBadMagic is returned if r does not start with a preamble.
+func Probe(r io.Reader) (Format, error) {
+	b := make([]byte, preambleSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return Format{}, ErrBadMagic
+		}
+		return Format{}, err
+	}
+	return decodePreamble(b)
+}
+
+var (
+	ErrBadMagic           = errors.New("stream does not start with the magic header")
+	ErrUnsupportedVersion = errors.New("unsupported stream format version")
+	ErrFormatMismatch     = errors.New("stream format differs from the options")
+)
@@ -0,0 +1,119 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMagicHeader(t *testing.T) {
+	for _, i := range []struct {
+		Opts   []Option
+		Format Format
+	}{
+		{nil, Format{Version: 1, HeaderSize: 16}},
+		{[]Option{WithChecksums(), WithBlockKinds()}, Format{Version: 1, HeaderSize: 24, Checksums: true}},
+		{[]Option{WithAlignInHeader(), WithInlineNames(), WithBackLink()}, Format{Version: 1, HeaderSize: 16, AlignInHeader: true, InlineNames: true, BackLink: true}},
+		{[]Option{WithFixedStride(64), WithFrameChecksum()}, Format{Version: 1, HeaderSize: 16, FixedStride: true, FrameChecksum: true}},
+		{[]Option{WithCodec(CodecGzip), WithIndexFooter(), WithHeaderFieldOrder(true)}, Format{Version: 1, HeaderSize: 24, OffsetFirst: true, IndexFooter: true, Codec: CodecGzip}},
+		{[]Option{WithBigEndian(), WithAlignInHeader(), WithBlockKinds(), WithChecksums(), WithFrameChecksum(), WithBackLink()}, Format{Version: 1, HeaderSize: 24, AlignInHeader: true, Checksums: true, FrameChecksum: true, BackLink: true, BigEndian: true}},
+	} {
+		opts := append(i.Opts, WithMagicHeader())
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		var offsets []int64
+		for _, block := range []string{"hello", "", "world"} {
+			offsets = append(offsets, writer.NextHeaderOffset())
+			writer.WriteString(block, 16)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("%+v: unexpected error: %v", i.Format, err)
+		}
+
+		if f, err := Probe(bytes.NewReader(buf.Bytes())); f != i.Format || err != nil {
+			t.Errorf("expected %+v; got %+v, %v", i.Format, f, err)
+		}
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		readerAt := NewByteBlockReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), opts...)
+		for j, expected := range []string{"hello", "", "world"} {
+			if data, err := slicer.Slice(); string(data) != expected || err != nil {
+				t.Errorf("%+v: expected %q; got %q, %v", i.Format, expected, data, err)
+			}
+			if data, err := reader.Read(); string(data) != expected || err != nil {
+				t.Errorf("%+v: expected %q; got %q, %v", i.Format, expected, data, err)
+			}
+			if data, err := readerAt.ReadBlock(int64(j)); string(data) != expected || err != nil {
+				t.Errorf("%+v: expected %q; got %q, %v", i.Format, expected, data, err)
+			}
+			if data, _, err := readerAt.ReadBlockAt(offsets[j]); string(data) != expected || err != nil {
+				t.Errorf("%+v: expected %q; got %q, %v", i.Format, expected, data, err)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("%+v: expected io.EOF; got %v", i.Format, err)
+		}
+		// The streaming reader reads an index footer as blocks.
+		if _, err := reader.Read(); err != io.EOF && !i.Format.IndexFooter {
+			t.Errorf("%+v: expected io.EOF; got %v", i.Format, err)
+		}
+
+		m, err := Manifest(buf.Bytes(), opts...)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %v", i.Format, err)
+		}
+		for j, b := range m.Blocks {
+			if b.Offset != offsets[j] {
+				t.Errorf("%+v: block %d: expected offset %d; got %d", i.Format, j, offsets[j], b.Offset)
+			}
+		}
+
+		// Reading with other options is caught.
+		if _, err := NewByteBlockSlicer(buf.Bytes(), WithMagicHeader(), WithBackLink()).Slice(); err != ErrFormatMismatch {
+			t.Errorf("%+v: expected ErrFormatMismatch; got %v", i.Format, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	NewByteBlockWriter(&buf).WriteString("hello", 8)
+	if _, err := Probe(bytes.NewReader(buf.Bytes())); err != ErrBadMagic {
+		t.Errorf("expected ErrBadMagic; got %v", err)
+	}
+	if _, err := Probe(strings.NewReader("BYTE")); err != ErrBadMagic {
+		t.Errorf("expected ErrBadMagic; got %v", err)
+	}
+	if _, err := NewByteBlockSlicer(buf.Bytes(), WithMagicHeader()).Slice(); err != ErrBadMagic {
+		t.Errorf("expected ErrBadMagic; got %v", err)
+	}
+	if _, err := NewByteBlockReader(bytes.NewReader(buf.Bytes()), WithMagicHeader()).Read(); err != ErrBadMagic {
+		t.Errorf("expected ErrBadMagic; got %v", err)
+	}
+	if _, err := NewByteBlockReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), WithMagicHeader()).ReadBlock(0); err != ErrBadMagic {
+		t.Errorf("expected ErrBadMagic; got %v", err)
+	}
+	future := encodePreamble(Format{Version: FormatVersion + 1})
+	if _, err := Probe(bytes.NewReader(future)); err != ErrUnsupportedVersion {
+		t.Errorf("expected ErrUnsupportedVersion; got %v", err)
+	}
+
+	buf.Reset()
+	writer := NewByteBlockWriter(&buf, WithMagicHeader(), WithCountHeader())
+	if err := writer.WriteCountHeader(1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	writer.WriteString("hello", 8)
+	if f, err := Probe(bytes.NewReader(buf.Bytes())); !f.CountHeader || err != nil {
+		t.Errorf("expected a count header; got %+v, %v", f, err)
+	}
+	if _, err := NewByteBlockSlicer(buf.Bytes(), WithMagicHeader()).Slice(); err != ErrFormatMismatch {
+		t.Errorf("expected ErrFormatMismatch; got %v", err)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithMagicHeader(), WithCountHeader())
+	if n, err := slicer.ReadCount(); n != 1 || err != nil {
+		t.Errorf("expected 1; got %d, %v", n, err)
+	}
+	if data, err := slicer.Slice(); string(data) != "hello" || err != nil {
+		t.Errorf("expected %q; got %q, %v", "hello", data, err)
+	}
+}
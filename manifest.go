@@ -0,0 +1,64 @@
+package byteblock
+
+import "io"
+
+// FileManifest describes the block layout of a stream. It marshals to
+// JSON for use by tools that do not parse the binary format.
+type FileManifest struct {
+	// Size is the number of bytes of the stream.
+	Size int64 `json:"size"`
+	// Blocks describes the blocks of the stream in order.
+	Blocks []BlockMeta `json:"blocks"`
+}
+
+// BlockMeta describes a block of a stream.
+type BlockMeta struct {
+	Index int `json:"index"`
+	// Offset is the position of the block header in the stream.
+	Offset int64 `json:"offset"`
+	// DataOffset is the position of the block data in the stream.
+	DataOffset int64 `json:"data_offset"`
+	// Length is the number of bytes of the block data.
+	Length int64 `json:"length"`
+	// Padding is the number of bytes between the header and the data.
+	Padding int64 `json:"padding"`
+	// Align is the alignment recorded in the header. It is only known
+	// for streams written with WithAlignInHeader.
+	Align int64 `json:"align,omitempty"`
+	// Name is the inline name of the block, if any.
+	Name string `json:"name,omitempty"`
+	// Kind is the kind of the block, if recorded.
+	Kind string `json:"kind,omitempty"`
+}
+
+// Manifest returns the block layout of data, which must be slicable to
+// the end with opts.
+func Manifest(data []byte, opts ...Option) (*FileManifest, error) {
+	m := &FileManifest{Size: int64(len(data)), Blocks: []BlockMeta{}}
+	slicer := NewByteBlockSlicer(data, opts...)
+	for {
+		info, h, name, _, err := slicer.sliceInfo()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		meta := BlockMeta{
+			Index:      int(info.Index),
+			Offset:     info.Offset,
+			DataOffset: info.DataOffset,
+			Length:     info.Length,
+			Padding:    info.Padding,
+			Name:       string(name),
+		}
+		if slicer.opts.offsetMode == offsetAlign {
+			_, offsetPos := slicer.opts.fieldPos()
+			meta.Align = slicer.opts.readField(slicer.data[info.Offset-slicer.basePos+int64(offsetPos):])
+		}
+		if slicer.opts.blockKinds {
+			meta.Kind = h.kind().String()
+		}
+		m.Blocks = append(m.Blocks, meta)
+	}
+}
@@ -0,0 +1,49 @@
+package byteblock
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithAlignInHeader(), WithInlineNames(), WithBlockKinds())
+	writer.WriteNamedInline("a", []byte("hello"), 16)
+	writer.Write([]byte("world!"), 64)
+	WriteParity(writer, [][]byte{[]byte("xy")}, 8)
+
+	m, err := Manifest(buf.Bytes(), WithAlignInHeader(), WithInlineNames(), WithBlockKinds())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded FileManifest
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Inline names take 2+len bytes between the aligned position and
+	// the data.
+	expected := FileManifest{
+		Size: int64(buf.Len()),
+		Blocks: []BlockMeta{
+			{Index: 0, Offset: 0, DataOffset: 35, Length: 5, Padding: 8, Align: 16, Name: "a", Kind: "data"},
+			{Index: 1, Offset: 40, DataOffset: 66, Length: 6, Padding: 0, Align: 64, Kind: "data"},
+			{Index: 2, Offset: 72, DataOffset: 98, Length: 2, Padding: 0, Align: 8, Kind: "parity"},
+		},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected %+v; got %+v", expected, decoded)
+	}
+
+	if m, err := Manifest(nil); err != nil || len(m.Blocks) != 0 {
+		t.Errorf("expected empty manifest; got %+v, %v", m, err)
+	}
+	if _, err := Manifest(buf.Bytes()[:buf.Len()-1], WithAlignInHeader(), WithInlineNames(), WithBlockKinds()); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
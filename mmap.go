@@ -0,0 +1,58 @@
+package byteblock
+
+import (
+	"errors"
+	"math"
+	"os"
+)
+
+// MappedSlicer is a ByteBlockSlicer over a memory-mapped file, created
+// by OpenFile.
+type MappedSlicer struct {
+	*ByteBlockSlicer
+	mapping []byte
+}
+
+// OpenFile memory-maps the file at path read-only and returns a slicer
+// of its blocks, so that big files need not be read into memory. The
+// slices returned by the slicer are views into the mapping, valid
+// until Close; using them afterwards may crash the program. On
+// platforms without memory mapping, the file is read into memory
+// instead.
+func OpenFile(path string, opts ...Option) (*MappedSlicer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size > math.MaxInt {
+		return nil, ErrFileTooLarge
+	}
+	var mapping []byte
+	if size > 0 {
+		if mapping, err = mmap(f, int(size)); err != nil {
+			return nil, err
+		}
+	}
+	return &MappedSlicer{ByteBlockSlicer: NewByteBlockSlicer(mapping, opts...), mapping: mapping}, nil
+}
+
+// Close unmaps the file. Later calls to the slicer return
+// ErrReaderClosed.
+func (r *MappedSlicer) Close() error {
+	if r.mapping == nil {
+		r.err = ErrReaderClosed
+		return nil
+	}
+	r.data, r.err = nil, ErrReaderClosed
+	err := munmap(r.mapping)
+	r.mapping = nil
+	return err
+}
+
+var ErrFileTooLarge = errors.New("file is too large to map")
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package byteblock
+
+import (
+	"io"
+	"os"
+)
+
+// mmap reads the first size bytes of f, for lack of memory mapping.
+func mmap(f *os.File, size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// munmap releases a buffer returned by mmap.
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package byteblock
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewByteBlockWriter(f, WithChecksums())
+	writer.WriteString("hello", 8)
+	writer.WriteString("world", 64)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	slicer, err := OpenFile(path, WithChecksums())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"hello", "world"} {
+		if data, err := slicer.Slice(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+	if err := slicer.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := slicer.Slice(); err != ErrReaderClosed {
+		t.Errorf("expected ErrReaderClosed; got %v", err)
+	}
+	if err := slicer.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	os.WriteFile(empty, nil, 0o644)
+	slicer, err = OpenFile(empty)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+	slicer.Close()
+
+	if _, err := OpenFile(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error; got %v", err)
+	}
+}
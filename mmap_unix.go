@@ -0,0 +1,18 @@
+//go:build unix
+
+package byteblock
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of f read-only.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap unmaps a mapping returned by mmap.
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
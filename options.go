@@ -0,0 +1,397 @@
+package byteblock
+
+import "time"
+
+// Option configures a ByteBlockWriter or a ByteBlockSlicer. Options
+// that only make sense on one side are ignored by the other.
+type Option func(*options)
+
+type options struct {
+	strict           bool
+	offsetMode       offsetMode
+	offsetFirst      bool
+	bigEndian        bool
+	inlineNames      bool
+	autoBlock        bool
+	autoBlockAlign   int64
+	capacity         int64
+	blockVersions    bool
+	blockKinds       bool
+	blockCodecs      bool
+	codec            Codec
+	keys             KeyProvider
+	macKey           []byte
+	stride           int64
+	backLink         bool
+	checksums        bool
+	frameChecksum    bool
+	indexFooter      bool
+	countHeader      bool
+	magicHeader      bool
+	maxLength        int64
+	syncMarkers      bool
+	startOffset      int64
+	maxHeaderPadding int64
+	readDeadline     time.Duration
+	baseOffset       int64
+	maxPadding       bool
+	maxPaddingRatio  float64
+	orderCheck       func(prevLength, length int64) bool
+}
+
+// offsetMode tells how the second header field is interpreted.
+type offsetMode int
+
+const (
+	offsetPadding  offsetMode = iota // amount of padding after the header
+	offsetAbsolute                   // absolute position of the data
+	offsetAlign                      // alignment of the data
+)
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithStrict makes the writer reject zero-length blocks with
+// ErrInvalidLength and non-positive alignments with ErrInvalidAlign,
+// instead of silently accepting them.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithAbsoluteOffset makes the second header field hold the absolute
+// position of the block data in the stream, instead of the amount of
+// padding after the header. The slicer then locates the data by that
+// position, which must not be before the end of the header;
+// otherwise ErrInvalidOffset is returned. It overrides
+// WithAlignInHeader.
+func WithAbsoluteOffset() Option {
+	return func(o *options) {
+		o.offsetMode = offsetAbsolute
+	}
+}
+
+// WithAlignInHeader makes the second header field hold the alignment
+// of the block data, instead of the amount of padding after the
+// header. The slicer then recomputes the padding from the alignment
+// and its own position, so a header stays valid wherever the block is
+// placed. In this mode, the alignment applies to the start of the
+// inline name, if any, rather than to the data after it. It overrides
+// WithAbsoluteOffset.
+func WithAlignInHeader() Option {
+	return func(o *options) {
+		o.offsetMode = offsetAlign
+	}
+}
+
+// WithHeaderFieldOrder sets the order of the first two header fields.
+// If offsetFirst is true, the offset field (see WithAbsoluteOffset and
+// WithAlignInHeader) comes before the length field, as in some other
+// formats; by default the length comes first.
+func WithHeaderFieldOrder(offsetFirst bool) Option {
+	return func(o *options) {
+		o.offsetFirst = offsetFirst
+	}
+}
+
+// WithBigEndian makes the fields of block headers and trailers, that is
+// the length, offset and flags, the checksums and the back link,
+// big-endian (network byte order) rather than little-endian, as some
+// wire protocols expect. The preamble, the count header, the index
+// footer and the encodings of block data, such as WriteStrings, are
+// unaffected.
+func WithBigEndian() Option {
+	return func(o *options) {
+		o.bigEndian = true
+	}
+}
+
+// WithInlineNames makes every block carry a name at the start of its
+// data, counted in the block length. The name is set with
+// ByteBlockWriter.WriteNamedInline and read back with
+// ByteBlockSlicer.SliceNamed; blocks written otherwise have an empty
+// name, and Slice returns the data without the name. Since each block
+// is self-labeled, names survive even if the stream is truncated.
+func WithInlineNames() Option {
+	return func(o *options) {
+		o.inlineNames = true
+	}
+}
+
+// WithAutoBlock makes ByteBlockWriter.Append and AppendString start a
+// new block with the given alignment when there is no unfinished
+// block, so that each such call writes a whole block like Write does.
+// Blocks explicitly started by NewBlock can still be filled by several
+// calls. Note that an empty Append after a finished block thus writes
+// an empty block.
+func WithAutoBlock(align int64) Option {
+	return func(o *options) {
+		o.autoBlock = true
+		o.autoBlockAlign = align
+	}
+}
+
+// WithBlockVersions makes every block header carry a version number,
+// set with ByteBlockWriter.NewBlockVer and read back with
+// ByteBlockSlicer.SliceVer, so that a stream can mix blocks of
+// different payload schemas. Blocks written otherwise have version 0.
+func WithBlockVersions() Option {
+	return func(o *options) {
+		o.blockVersions = true
+	}
+}
+
+// WithBlockKinds makes every block header record the kind of the
+// block, such as KindParity for blocks written by WriteParity, which
+// ByteBlockSlicer.SliceKind reports. Blocks written otherwise are of
+// KindData.
+func WithBlockKinds() Option {
+	return func(o *options) {
+		o.blockKinds = true
+	}
+}
+
+// WithBlockCodecs makes every block header record the codec its data
+// is compressed with, so that ByteBlockWriter.WriteCodec can mix raw
+// and compressed blocks in a stream and ByteBlockSlicer.SliceCodec can
+// decompress each accordingly. Blocks written otherwise use CodecNone.
+func WithBlockCodecs() Option {
+	return func(o *options) {
+		o.blockCodecs = true
+	}
+}
+
+// WithCodec makes the writer compress the data of every block with
+// codec, and slicers and readers decompress the data of every block
+// according to the codec recorded in its header, so that compression
+// is transparent to callers. As the data of a block is gathered in
+// memory until it is complete, NextHeaderOffset is only meaningful
+// between blocks. Empty blocks, blocks started by NewBlockAt and inline
+// names are not compressed, and neither is data that
+// EstimateCompressible deems incompressible, which is stored raw with
+// CodecNone in its header. It implies WithBlockCodecs.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.blockCodecs = true
+		o.codec = codec
+	}
+}
+
+// WithEncryption makes the writer seal the data of every block with
+// AES-GCM, under the key given by keys.SealingKey, and slicers and
+// readers open it with the key of the ID recorded in its header, so
+// that encryption is transparent to callers. The data is compressed
+// first with WithCodec. Sealing gathers the data of a block in memory.
+// Every block with data is sealed, along with its header flags and
+// position, so that blocks cannot be moved or reordered unnoticed;
+// slicers and readers return ErrBlockNotEncrypted for a block with
+// data that is not sealed. It implies WithBlockCodecs.
+func WithEncryption(keys KeyProvider) Option {
+	return func(o *options) {
+		o.blockCodecs = true
+		o.keys = keys
+	}
+}
+
+// WithFixedStride makes every block, including its header, padding
+// and any trailing padding after its data, take exactly stride bytes,
+// so that block i starts at i*stride and can be sliced directly by
+// ByteBlockSlicer.SliceStride. The writer returns ErrBlockTooLarge for
+// blocks that do not fit.
+func WithFixedStride(stride int64) Option {
+	return func(o *options) {
+		o.stride = stride
+	}
+}
+
+// WithBackLink makes every block end with an int64 holding its total
+// size, from the start of its header to the end of this field, so that
+// ByteBlockSlicer.SliceReverseLinked can walk the stream backward from
+// its end. With WithFixedStride, the field takes the last 8 bytes of
+// the stride.
+func WithBackLink() Option {
+	return func(o *options) {
+		o.backLink = true
+	}
+}
+
+// WithChecksums makes every block end with a CRC-32C checksum of its
+// data, inline name included, which slicers and readers verify,
+// returning ErrChecksumMismatch for blocks that do not match. The
+// checksum comes right after the data and any trailing padding of
+// WithFixedStride, within the stride.
+func WithChecksums() Option {
+	return func(o *options) {
+		o.checksums = true
+	}
+}
+
+// WithFrameChecksum makes every block end with a CRC-32C checksum of
+// the whole block up to it, header and padding included, so that
+// corrupted headers are detected as well as corrupted data. Slicers
+// and readers return ErrFrameChecksumMismatch for blocks that do not
+// match. Unlike WithChecksums, it covers headers too. The checksum
+// comes after that of WithChecksums, before the back link of
+// WithBackLink and, with WithFixedStride, is part of the stride.
+func WithFrameChecksum() Option {
+	return func(o *options) {
+		o.frameChecksum = true
+	}
+}
+
+// WithStreamMAC makes the writer keep an HMAC-SHA256 of all the bytes
+// of the stream under key, and ByteBlockWriter.Close write it in a
+// trailer block at the very end, after any index footer, so that
+// tampering with an archived stream is detected. Slicers verify it
+// upfront and stop before the trailer; ByteBlockReaderAt stops before
+// it without verifying it, which VerifyMAC does. ByteBlockReader hides
+// the trailer and verifies the MAC as it reads, returning io.EOF at
+// the end of the stream only if it matches, so that Validate, Merge
+// and Split verify it too; the last block of the stream is only
+// returned once the trailer has been read. Streams with a MAC cannot
+// be appended to, and blocks cannot be filled in later with
+// NewBlockUnknownLength or ReserveBlock.
+func WithStreamMAC(key []byte) Option {
+	return func(o *options) {
+		o.macKey = key
+	}
+}
+
+// WithCountHeader makes streams start with the number of blocks they
+// hold, written by ByteBlockWriter.WriteCountHeader before any block
+// and after the preamble of WithMagicHeader, so that readers can learn
+// it upfront. Slicers and readers skip it and return it from ReadCount,
+// while ByteBlockReaderAt and Layout account for it.
+func WithCountHeader() Option {
+	return func(o *options) {
+		o.countHeader = true
+	}
+}
+
+// WithIndexFooter makes ByteBlockWriter.Close write an index of all
+// blocks, followed by a footer locating it, at the end of the stream.
+// LoadIndex reads the index back, and ByteBlockReaderAt.ReadBlock uses
+// it to locate blocks directly. Slicers stop before the index, while
+// streaming readers return the index and the footer as two more
+// blocks.
+func WithIndexFooter() Option {
+	return func(o *options) {
+		o.indexFooter = true
+	}
+}
+
+// WithReadDeadline makes ByteBlockReader give up on a block that takes
+// longer than d to read, if the underlying reader has a
+// SetReadDeadline(time.Time) error method, as net.Conn does. The
+// deadline is set anew before each block. An expired deadline is
+// reported as an error matching both ErrReadTimeout and
+// os.ErrDeadlineExceeded.
+func WithReadDeadline(d time.Duration) Option {
+	return func(o *options) {
+		o.readDeadline = d
+	}
+}
+
+// WithInitialCapacity hints that the writer will write about n bytes
+// in total. If the underlying writer has a Grow(int) method, like
+// *bytes.Buffer, it is called with n at construction to avoid
+// reallocations while writing; otherwise the hint is ignored.
+func WithInitialCapacity(n int64) Option {
+	return func(o *options) {
+		o.capacity = n
+	}
+}
+
+// WithBaseOffset tells the writer that the stream starts at position
+// base of the underlying file, such as after a file header. Since
+// alignments are relative to the start of the stream, data is only
+// aligned in the file for alignments that divide base, so
+// ByteBlockWriter.NewBlock returns ErrAlignmentUnsatisfiable for
+// power-of-two alignments that do not.
+func WithBaseOffset(base int64) Option {
+	return func(o *options) {
+		o.baseOffset = base
+	}
+}
+
+// WithStartOffset tells the writer that the stream already holds n
+// bytes, such as when appending to an existing stream, so that new
+// blocks are aligned and positioned as if the writer had written them.
+// No preamble is written then, see WithMagicHeader. Streams with an
+// index footer cannot be appended to. See also OpenAppend.
+func WithStartOffset(n int64) Option {
+	return func(o *options) {
+		o.startOffset = n
+	}
+}
+
+// WithMaxPaddingRatio makes ByteBlockWriter.NewBlock return
+// ErrExcessivePadding when aligning a non-empty block would take more
+// than r times its length in padding, which guards against alignments
+// that bloat streams of small blocks.
+func WithMaxPaddingRatio(r float64) Option {
+	return func(o *options) {
+		o.maxPadding = true
+		o.maxPaddingRatio = r
+	}
+}
+
+// WithOrderCheck makes the slicer check that the lengths of every two
+// consecutive blocks satisfy less, such as strictly increasing lengths,
+// and return ErrOrderViolation otherwise. The lengths exclude inline
+// names. ByteBlockSlicer.SliceStride starts the check anew.
+func WithOrderCheck(less func(prevLength, length int64) bool) Option {
+	return func(o *options) {
+		o.orderCheck = less
+	}
+}
+
+// WithMagicHeader makes the writer start the stream with a preamble of
+// magic bytes, the format version and the format options, see Format.
+// Readers check the preamble and return ErrBadMagic,
+// ErrUnsupportedVersion or ErrFormatMismatch if the stream was not
+// written with the same options, rather than misreading it. Stream
+// positions, such as those of NextHeaderOffset, count the preamble.
+func WithMagicHeader() Option {
+	return func(o *options) {
+		o.magicHeader = true
+	}
+}
+
+// WithHeaderLimits makes readers reject, with ErrLengthLimit or
+// ErrPaddingLimit, block headers whose length exceeds maxLength or
+// whose padding exceeds maxPadding, so that a corrupted or malicious
+// stream cannot cause huge allocations, such as in
+// ByteBlockReader.Read. The length includes the inline name, if any.
+// Data decompressed with WithCodec or SliceCodec is limited to
+// maxLength too. A non-positive limit means no limit.
+func WithHeaderLimits(maxLength, maxPadding int64) Option {
+	return func(o *options) {
+		o.maxLength = maxLength
+		o.maxHeaderPadding = maxPadding
+	}
+}
+
+// WithSyncMarkers makes every block header start with a fixed 8-byte
+// sync marker, which readers check. A RecoveringReader looks for the
+// markers to resume after damaged parts of a stream.
+func WithSyncMarkers() Option {
+	return func(o *options) {
+		o.syncMarkers = true
+	}
+}
+
+// inlineNameSize returns the number of bytes name takes at the start
+// of the block data.
+func (o *options) inlineNameSize(name string) int64 {
+	if !o.inlineNames {
+		return 0
+	}
+	return 2 + int64(len(name))
+}
@@ -0,0 +1,183 @@
+package byteblock
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"runtime"
+	"sync"
+)
+
+// ParallelWriter writes blocks to a ByteBlockWriter from several
+// goroutines. The data of each block is prepared, compressed with the
+// codec of WithCodec and checksummed by a pool of workers, while a
+// single sequencer writes the blocks in the order they were submitted.
+// As sealed data is bound to the position of its block, blocks are
+// sealed with WithEncryption, and then checksummed, by the sequencer.
+// It is safe for concurrent use; blocks submitted concurrently are
+// written in the order their submissions were accepted.
+type ParallelWriter struct {
+	w        *ByteBlockWriter
+	workers  chan struct{}       // bounds the blocks prepared at once
+	queue    chan *parallelBlock // blocks in submission order
+	done     chan struct{}       // closed when the sequencer returns
+	submitMu sync.Mutex          // orders submissions
+	closed   bool
+	errMu    sync.Mutex
+	err      error
+}
+
+// parallelBlock is a block submitted to a ParallelWriter.
+type parallelBlock struct {
+	align int64
+	data  []byte
+	flags uint64 // header flags for the compression
+	sum   uint32
+	err   error
+	ready chan struct{} // closed once the block is prepared
+}
+
+// NewParallelWriter creates a ParallelWriter that prepares up to
+// workers blocks at once and writes them to w, which must not be used
+// until Close returns. If workers is not positive, runtime.GOMAXPROCS
+// workers are used.
+func NewParallelWriter(w *ByteBlockWriter, workers int) *ParallelWriter {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &ParallelWriter{
+		w:       w,
+		workers: make(chan struct{}, workers),
+		queue:   make(chan *parallelBlock, workers),
+		done:    make(chan struct{}),
+	}
+	go p.sequence()
+	return p
+}
+
+// Write submits data as a block with the given alignment. data must
+// not be modified until Close returns.
+func (p *ParallelWriter) Write(data []byte, align int64) error {
+	return p.Submit(align, func() ([]byte, error) { return data, nil })
+}
+
+// Submit submits a block with the given alignment whose data is
+// returned by prepare, which is called on a worker goroutine. The
+// block is written after all the blocks submitted before it. Submit
+// blocks while too many blocks are waiting to be written, and returns
+// the first error of any block submitted so far, in which case the
+// block is not submitted. An error returned by prepare stops the
+// writer. ErrWriterClosed is returned after Close.
+func (p *ParallelWriter) Submit(align int64, prepare func() ([]byte, error)) error {
+	p.submitMu.Lock()
+	defer p.submitMu.Unlock()
+	if p.closed {
+		return ErrWriterClosed
+	}
+	if err := p.firstErr(); err != nil {
+		return err
+	}
+	b := &parallelBlock{align: align, ready: make(chan struct{})}
+	p.queue <- b
+	go p.prepare(b, prepare)
+	return nil
+}
+
+// prepare prepares the data of b on a worker.
+func (p *ParallelWriter) prepare(b *parallelBlock, prepare func() ([]byte, error)) {
+	defer close(b.ready)
+	p.workers <- struct{}{}
+	defer func() { <-p.workers }()
+	opts := &p.w.opts
+	if b.data, b.err = prepare(); b.err != nil {
+		return
+	}
+	if len(b.data) > 0 {
+		if b.data, b.flags, b.err = opts.compressData(b.data, 0); b.err != nil {
+			return
+		}
+	}
+	if opts.checksums && opts.keys == nil {
+		if opts.inlineNames {
+			// The checksum covers the empty inline name that
+			// newBlock writes before the data.
+			var name [2]byte
+			b.sum = crc32.Update(0, castagnoliTable, name[:])
+		}
+		b.sum = crc32.Update(b.sum, castagnoliTable, b.data)
+	}
+}
+
+// sequence writes the submitted blocks in order until Close.
+func (p *ParallelWriter) sequence() {
+	defer close(p.done)
+	for b := range p.queue {
+		<-b.ready
+		if p.firstErr() != nil {
+			// Drain the queue so that Submit does not block.
+			continue
+		}
+		err := b.err
+		if err == nil {
+			err = p.write(b)
+		}
+		if err != nil {
+			p.errMu.Lock()
+			p.err = err
+			p.errMu.Unlock()
+		}
+	}
+}
+
+// write writes the prepared block b.
+func (p *ParallelWriter) write(b *parallelBlock) error {
+	w := p.w
+	if w.opts.keys != nil && len(b.data) > 0 {
+		if b.data, b.flags, w.err = w.opts.sealData(b.data, b.flags, w.numBytesWritten); w.err != nil {
+			return w.err
+		}
+	} else if w.payloadHash != nil {
+		// The data was checksummed by the worker.
+		saved := w.payloadHash
+		w.payloadHash = &presetHash{sum: b.sum}
+		defer func() { w.payloadHash = saved }()
+	}
+	// The data is never compressed again by newBlock.
+	meta := blockMeta{flags: b.flags, raw: true}
+	if w.err = w.newBlock(b.align, int64(len(b.data)), meta); w.err != nil {
+		return w.err
+	}
+	return w.append(b.data)
+}
+
+// firstErr returns the first error of the blocks written so far.
+func (p *ParallelWriter) firstErr() error {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	return p.err
+}
+
+// Close waits until all the submitted blocks are written, and returns
+// the first error, if any. It does not close the ByteBlockWriter.
+func (p *ParallelWriter) Close() error {
+	p.submitMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.submitMu.Unlock()
+	<-p.done
+	return p.firstErr()
+}
+
+// presetHash is a hash.Hash32 whose sum is already known, which lets
+// ParallelWriter checksum the data of blocks on its workers.
+type presetHash struct {
+	sum uint32
+}
+
+func (h *presetHash) Write(p []byte) (int, error) { return len(p), nil }
+func (h *presetHash) Sum(b []byte) []byte         { return binary.BigEndian.AppendUint32(b, h.sum) }
+func (h *presetHash) Reset()                      {}
+func (h *presetHash) Size() int                   { return crc32.Size }
+func (h *presetHash) BlockSize() int              { return 1 }
+func (h *presetHash) Sum32() uint32               { return h.sum }
@@ -0,0 +1,97 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParallelWriter(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithChecksums(), WithFrameChecksum()},
+		{WithCodec(CodecGzip), WithChecksums(), WithBackLink()},
+		{WithEncryption(StaticKey(bytes.Repeat([]byte("k"), 16))), WithChecksums()},
+		{WithChecksums(), WithInlineNames()},
+		{WithCodec(CodecFlate), WithChecksums(), WithInlineNames()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		p := NewParallelWriter(writer, 4)
+		var expected []string
+		for i := 0; i < 100; i++ {
+			block := strings.Repeat(fmt.Sprint(i), i)
+			expected = append(expected, block)
+			if i%2 == 0 {
+				if err := p.Write([]byte(block), 8); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				continue
+			}
+			if err := p.Submit(16, func() ([]byte, error) { return []byte(block), nil }); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if err := p.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := p.Write([]byte("late"), 8); err != ErrWriterClosed {
+			t.Errorf("expected ErrWriterClosed; got %v", err)
+		}
+		// The writer can still be used after Close.
+		writer.WriteString("last", 8)
+		expected = append(expected, "last")
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		for _, e := range expected {
+			if data, err := slicer.Slice(); string(data) != e || err != nil {
+				t.Fatalf("expected %q; got %q, %v", e, data, err)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+	}
+
+	// Concurrent submissions all make it.
+	var buf bytes.Buffer
+	p := NewParallelWriter(NewByteBlockWriter(&buf), 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				p.Write([]byte("block"), 8)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := p.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, err := Count(buf.Bytes()); n != 80 || err != nil {
+		t.Errorf("expected 80; got %d, %v", n, err)
+	}
+
+	// The first error stops the writer.
+	buf.Reset()
+	errPrepare := errors.New("prepare failed")
+	p = NewParallelWriter(NewByteBlockWriter(&buf), 2)
+	p.Write([]byte("first"), 8)
+	p.Submit(8, func() ([]byte, error) { return nil, errPrepare })
+	p.Write([]byte("dropped"), 8)
+	if err := p.Close(); err != errPrepare {
+		t.Errorf("expected %v; got %v", errPrepare, err)
+	}
+	if n, err := Count(buf.Bytes()); n != 1 || err != nil {
+		t.Errorf("expected 1; got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,40 @@
+package byteblock
+
+// WriteParity writes the byte-wise XOR of blocks, with shorter blocks
+// padded with zeros to the longest one, as a block of KindParity. The
+// writer must have been created with WithBlockKinds; otherwise
+// ErrBlockKindsDisabled is returned. Any one of blocks can then be
+// recovered from the others and the parity block with RecoverMissing.
+func WriteParity(w *ByteBlockWriter, blocks [][]byte, align int64) error {
+	size := 0
+	for _, b := range blocks {
+		if len(b) > size {
+			size = len(b)
+		}
+	}
+	parity := make([]byte, size)
+	for _, b := range blocks {
+		xorInto(parity, b)
+	}
+	return w.writeKind(KindParity, parity, align)
+}
+
+// RecoverMissing reconstructs the one block missing from present
+// using the parity block written by WriteParity for all of them. As
+// blocks are padded for the parity computation, the result has the
+// length of parity and is padded with zeros if the missing block was
+// shorter.
+func RecoverMissing(present [][]byte, parity []byte) []byte {
+	missing := append([]byte(nil), parity...)
+	for _, b := range present {
+		xorInto(missing, b)
+	}
+	return missing
+}
+
+// xorInto XORs src into the start of dst, which must not be shorter.
+func xorInto(dst, src []byte) {
+	for i, b := range src {
+		dst[i] ^= b
+	}
+}
@@ -0,0 +1,54 @@
+package byteblock
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParity(t *testing.T) {
+	blocks := [][]byte{[]byte("hello"), []byte("wonderful"), []byte(""), []byte("world!")}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithBlockKinds())
+	for _, b := range blocks {
+		writer.Write(b, 8)
+	}
+	if err := WriteParity(writer, blocks, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithBlockKinds())
+	for i := range blocks {
+		if _, kind, err := slicer.SliceKind(); kind != KindData || err != nil {
+			t.Errorf("block %d: expected data; got %v, %v", i, kind, err)
+		}
+	}
+	parity, kind, err := slicer.SliceKind()
+	if kind != KindParity || err != nil {
+		t.Fatalf("expected parity; got %v, %v", kind, err)
+	}
+	if len(parity) != len("wonderful") {
+		t.Errorf("expected parity of the longest block length; got %d", len(parity))
+	}
+
+	for missing := range blocks {
+		var present [][]byte
+		for i, b := range blocks {
+			if i != missing {
+				present = append(present, b)
+			}
+		}
+		recovered := RecoverMissing(present, parity)
+		expected := make([]byte, len(parity))
+		copy(expected, blocks[missing])
+		if !bytes.Equal(recovered, expected) {
+			t.Errorf("block %d: expected %q; got %q", missing, expected, recovered)
+		}
+	}
+
+	if err := WriteParity(NewByteBlockWriter(&buf), blocks, 8); err != ErrBlockKindsDisabled {
+		t.Errorf("expected ErrBlockKindsDisabled; got %v", err)
+	}
+	if _, _, err := NewByteBlockSlicer(buf.Bytes()).SliceKind(); err != ErrBlockKindsDisabled {
+		t.Errorf("expected ErrBlockKindsDisabled; got %v", err)
+	}
+}
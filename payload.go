@@ -0,0 +1,39 @@
+package byteblock
+
+import "io"
+
+// NewPayloadReader returns a reader of the concatenated data of the
+// blocks in data, without headers and padding, like the payload of
+// Flatten but without copying it up front. Reads do not stop at block
+// boundaries. Slicing errors are returned by Read once the data before
+// them is consumed.
+func NewPayloadReader(data []byte, opts ...Option) io.Reader {
+	return &payloadReader{slicer: NewByteBlockSlicer(data, opts...)}
+}
+
+type payloadReader struct {
+	slicer *ByteBlockSlicer
+	// block holds the unread data of the current block.
+	block []byte
+}
+
+func (r *payloadReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.block) == 0 {
+			block, err := r.slicer.Slice()
+			if err != nil {
+				if n > 0 {
+					return n, nil
+				}
+				return 0, err
+			}
+			r.block = block
+			continue
+		}
+		m := copy(p[n:], r.block)
+		r.block = r.block[m:]
+		n += m
+	}
+	return n, nil
+}
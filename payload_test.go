@@ -0,0 +1,43 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestPayloadReader(t *testing.T) {
+	blocks := []string{"hello", "", "wonderful", "w", "orld!"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithInlineNames())
+	for _, b := range blocks {
+		writer.WriteString(b, 16)
+	}
+
+	for _, size := range []int{1, 2, 3, 7, 100} {
+		reader := NewPayloadReader(buf.Bytes(), WithInlineNames())
+		var got []byte
+		p := make([]byte, size)
+		for {
+			n, err := reader.Read(p)
+			got = append(got, p[:n]...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("size %d: unexpected error: %v", size, err)
+			}
+		}
+		if string(got) != "hellowonderfulworld!" {
+			t.Errorf("size %d: got %q", size, got)
+		}
+	}
+
+	got, err := io.ReadAll(NewPayloadReader(buf.Bytes()[:buf.Len()-1], WithInlineNames()))
+	if err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+	if string(got) != "hellowonderfulw" {
+		t.Errorf("got %q", got)
+	}
+}
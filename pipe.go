@@ -0,0 +1,29 @@
+package byteblock
+
+import (
+	"bufio"
+	"io"
+)
+
+// pipeBufferSize is the size of the buffer that gathers the pieces of
+// a block written through NewPipe.
+const pipeBufferSize = 64 << 10
+
+// NewPipe creates a ByteBlockWriter and a ByteBlockReader connected by
+// an io.Pipe, so that blocks can be produced in one goroutine and
+// consumed in another. Headers, padding and data of a block are
+// buffered and handed over together once the block is finished, rather
+// than one small write at a time; blocks bigger than the buffer are
+// handed over in several writes. As with io.Pipe, each write blocks
+// until the reader has consumed it, so the reader must keep reading
+// until the writer is closed. Closing the writer makes the reader
+// return io.EOF after the last block, or, if Close fails, such as for
+// an unfinished block, that error instead.
+func NewPipe(opts ...Option) (*ByteBlockWriter, *ByteBlockReader) {
+	pr, pw := io.Pipe()
+	buf := bufio.NewWriterSize(pw, pipeBufferSize)
+	writer := NewByteBlockWriter(buf, opts...)
+	writer.flusher = buf
+	writer.closer = pw
+	return writer, NewByteBlockReader(pr, opts...)
+}
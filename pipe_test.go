@@ -0,0 +1,74 @@
+package byteblock
+
+import (
+	"bufio"
+	"io"
+	"testing"
+)
+
+// countingWriter counts the calls to Write.
+type countingWriter struct {
+	w     io.Writer
+	calls int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	return w.w.Write(p)
+}
+
+func TestPipe(t *testing.T) {
+	blocks := []string{"hello", "", "world", string(make([]byte, 100000)), "!"}
+	writer, reader := NewPipe(WithInlineNames())
+	counter := &countingWriter{w: writer.closer.(*io.PipeWriter)}
+	writer.flusher.(*bufio.Writer).Reset(counter)
+
+	errc := make(chan error, 1)
+	go func() {
+		for _, b := range blocks {
+			if err := writer.WriteString(b, 16); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- writer.Close()
+	}()
+
+	for _, expected := range blocks {
+		if data, err := reader.Read(); string(data) != expected || err != nil {
+			t.Errorf("expected %d bytes; got %d, %v", len(expected), len(data), err)
+		}
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// One write per small block; the big one is written past the
+	// buffer.
+	if counter.calls > len(blocks)+2 {
+		t.Errorf("expected few writes; got %d", counter.calls)
+	}
+}
+
+func TestPipeWriterFailure(t *testing.T) {
+	writer, reader := NewPipe()
+	go func() {
+		writer.WriteString("hello", 8)
+		writer.NewBlock(8, 10)
+		writer.Append([]byte("short"))
+		writer.Close()
+	}()
+
+	if data, err := reader.Read(); string(data) != "hello" || err != nil {
+		t.Errorf("expected %q; got %q, %v", "hello", data, err)
+	}
+	// The unfinished block fails the reader rather than blocking it.
+	if _, err := reader.Read(); err != ErrBlockNotFinished {
+		t.Errorf("expected ErrBlockNotFinished; got %v", err)
+	}
+	if err := writer.Close(); err != ErrBlockNotFinished {
+		t.Errorf("expected ErrBlockNotFinished; got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package byteblock
+
+// BlocksThatFit returns how many blocks of the given lengths, written
+// in order with the given alignment from position startPos in the
+// stream, fit entirely within budget bytes, counting headers, padding
+// and anything else the options add to blocks. This helps packing
+// blocks into fixed-size pages.
+func BlocksThatFit(lengths []int64, align, startPos, budget int64, opts ...Option) int {
+	o := newOptions(opts)
+	pos := startPos
+	for i, length := range lengths {
+		pos = o.blockEnd(pos, align, length)
+		if pos-startPos > budget {
+			return i
+		}
+	}
+	return len(lengths)
+}
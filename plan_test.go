@@ -0,0 +1,43 @@
+package byteblock
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlocksThatFit(t *testing.T) {
+	lengths := []int64{10, 0, 30, 5, 100}
+	// Relative to the start, blocks end at 32, 54, 100, 123 and 242.
+	for _, i := range []struct {
+		Budget int64
+		Count  int
+	}{
+		{0, 0}, {31, 0}, {32, 1}, {53, 1}, {54, 2}, {99, 2}, {100, 3}, {241, 4}, {242, 5},
+	} {
+		if n := BlocksThatFit(lengths, 8, 10, i.Budget); n != i.Count {
+			t.Errorf("budget %d: expected %d; got %d", i.Budget, i.Count, n)
+		}
+	}
+
+	// Check against the writer with various options.
+	for _, opts := range [][]Option{
+		nil,
+		{WithInlineNames()},
+		{WithInlineNames(), WithAlignInHeader(), WithBlockVersions()},
+		{WithFrameChecksum(), WithBackLink()},
+		{WithFixedStride(256)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, length := range lengths {
+			writer.Write(make([]byte, length), 8)
+			budget := int64(buf.Len())
+			if n := BlocksThatFit(lengths, 8, 0, budget, opts...); n != int(writer.numBlocks) {
+				t.Errorf("budget %d: expected %d; got %d", budget, writer.numBlocks, n)
+			}
+			if n := BlocksThatFit(lengths, 8, 0, budget-1, opts...); n != int(writer.numBlocks)-1 {
+				t.Errorf("budget %d: expected %d; got %d", budget-1, writer.numBlocks-1, n)
+			}
+		}
+	}
+}
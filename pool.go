@@ -0,0 +1,64 @@
+package byteblock
+
+import (
+	"io"
+	"sync"
+)
+
+// pooledBuffer is a buffer recycled through bufferPool. Its release
+// function is created once along with it so that handing it out does
+// not allocate.
+type pooledBuffer struct {
+	data    []byte
+	release func()
+}
+
+var bufferPool sync.Pool
+
+// getPooledBuffer returns a pooled buffer of n bytes.
+func getPooledBuffer(n int) *pooledBuffer {
+	b, _ := bufferPool.Get().(*pooledBuffer)
+	if b == nil {
+		b = &pooledBuffer{}
+		b.release = func() { bufferPool.Put(b) }
+	}
+	if cap(b.data) < n {
+		b.data = make([]byte, n)
+	}
+	b.data = b.data[:n]
+	return b
+}
+
+// SlicePooled is like Slice except that it returns a copy of the block
+// data in a buffer taken from a pool shared by all slicers, along with
+// a function that returns the buffer to the pool. This gives an owned
+// copy without allocating for every block. The copy must not be used
+// after calling release, and release must be called at most once.
+func (r *ByteBlockSlicer) SlicePooled() (data []byte, release func(), err error) {
+	block, err := r.Slice()
+	if err != nil {
+		return nil, nil, err
+	}
+	b := getPooledBuffer(len(block))
+	copy(b.data, block)
+	return b.data, b.release, nil
+}
+
+// SliceWithAlloc is like Slice except that it returns a copy of the
+// block data in a buffer obtained from alloc, which is called with the
+// length of the block. This lets callers place the copy in memory they
+// manage, such as an arena. If alloc returns fewer than n bytes,
+// io.ErrShortBuffer is returned and the block is still consumed;
+// extra bytes are sliced off.
+func (r *ByteBlockSlicer) SliceWithAlloc(alloc func(n int64) []byte) ([]byte, error) {
+	block, err := r.Slice()
+	if err != nil {
+		return nil, err
+	}
+	data := alloc(int64(len(block)))
+	if len(data) < len(block) {
+		return nil, io.ErrShortBuffer
+	}
+	data = data[:copy(data, block)]
+	return data, nil
+}
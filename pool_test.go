@@ -0,0 +1,104 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSlicePooled(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	blocks := []string{"hello", "", "world", "a longer block than the others"}
+	for _, b := range blocks {
+		writer.WriteString(b, 8)
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range blocks {
+		data, release, err := slicer.SlicePooled()
+		if string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+		if len(data) > 0 && bytes.Contains(buf.Bytes(), data) {
+			// Modifying the copy must leave the backing data intact.
+			data[0] ^= 0xff
+			if !bytes.Contains(buf.Bytes(), []byte(expected)) {
+				t.Errorf("%q: expected a copy", expected)
+			}
+		}
+		release()
+	}
+	if _, _, err := slicer.SlicePooled(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+}
+
+func TestSliceWithAlloc(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	blocks := []string{"hello", "", "world!"}
+	for _, b := range blocks {
+		writer.WriteString(b, 8)
+	}
+	arena := make([]byte, 0, 64)
+	var sizes []int64
+	alloc := func(n int64) []byte {
+		sizes = append(sizes, n)
+		arena = arena[:len(arena)+int(n)]
+		return arena[len(arena)-int(n):]
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, expected := range blocks {
+		data, err := slicer.SliceWithAlloc(alloc)
+		if string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	if expected := []int64{5, 0, 6}; !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("expected allocations %v; got %v", expected, sizes)
+	}
+	if string(arena) != "helloworld!" {
+		t.Errorf("expected copies in the arena; got %q", arena)
+	}
+
+	slicer = NewByteBlockSlicer(buf.Bytes())
+	if _, err := slicer.SliceWithAlloc(func(n int64) []byte { return nil }); err != io.ErrShortBuffer {
+		t.Errorf("expected io.ErrShortBuffer; got %v", err)
+	}
+}
+
+func benchmarkSliceCopies(b *testing.B, slice func(*ByteBlockSlicer) error) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for i := 0; i < 100; i++ {
+		writer.Write(make([]byte, 4096), 8)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		slicer := NewByteBlockSlicer(buf.Bytes())
+		for slice(slicer) == nil {
+		}
+	}
+}
+
+func BenchmarkSliceCopy(b *testing.B) {
+	benchmarkSliceCopies(b, func(r *ByteBlockSlicer) error {
+		data, err := r.Slice()
+		if err == nil {
+			_ = append([]byte(nil), data...)
+		}
+		return err
+	})
+}
+
+func BenchmarkSlicePooled(b *testing.B) {
+	benchmarkSliceCopies(b, func(r *ByteBlockSlicer) error {
+		_, release, err := r.SlicePooled()
+		if err == nil {
+			release()
+		}
+		return err
+	})
+}
@@ -0,0 +1,80 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+)
+
+// PrefetchReader reads blocks from an io.ReaderAt ahead of time on a
+// background goroutine, so that reading overlaps with processing.
+type PrefetchReader struct {
+	blocks chan prefetchedBlock
+	done   chan struct{}
+	exited chan struct{}
+	err    error
+}
+
+type prefetchedBlock struct {
+	data []byte
+	err  error
+}
+
+// NewPrefetchReader creates a reader of the blocks in the first size
+// bytes of ra, which keeps up to depth blocks read ahead. Close must be
+// called to stop the background goroutine if not all blocks are read.
+func NewPrefetchReader(ra io.ReaderAt, size int64, depth int, opts ...Option) *PrefetchReader {
+	if depth < 1 {
+		depth = 1
+	}
+	r := &PrefetchReader{
+		blocks: make(chan prefetchedBlock, depth),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	reader := NewByteBlockReader(io.NewSectionReader(ra, 0, size), opts...)
+	go r.prefetch(reader)
+	return r
+}
+
+// prefetch sends the blocks of reader to r.blocks until an error,
+// including io.EOF, or until r is closed.
+func (r *PrefetchReader) prefetch(reader *ByteBlockReader) {
+	defer close(r.exited)
+	for {
+		data, err := reader.Read()
+		select {
+		case r.blocks <- prefetchedBlock{data, err}:
+		case <-r.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read returns the next block, waiting for it to be read if needed. It
+// returns io.EOF after the last block, and ErrReaderClosed after Close.
+// Errors are sticky.
+func (r *PrefetchReader) Read() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	b := <-r.blocks
+	r.err = b.err
+	return b.data, b.err
+}
+
+// Close stops reading ahead and waits for the background goroutine to
+// exit. Blocks read ahead are dropped.
+func (r *PrefetchReader) Close() error {
+	if r.err == ErrReaderClosed {
+		return nil
+	}
+	close(r.done)
+	<-r.exited
+	r.err = ErrReaderClosed
+	return nil
+}
+
+var ErrReaderClosed = errors.New("reader is closed")
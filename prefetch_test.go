@@ -0,0 +1,56 @@
+package byteblock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestPrefetchReader(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithInlineNames())
+	var blocks []string
+	for i := 0; i < 100; i++ {
+		blocks = append(blocks, fmt.Sprint("block ", i))
+		writer.WriteString(blocks[i], 8)
+	}
+	ra := bytes.NewReader(buf.Bytes())
+
+	reader := NewPrefetchReader(ra, int64(buf.Len()), 4, WithInlineNames())
+	for _, expected := range blocks {
+		if data, err := reader.Read(); string(data) != expected || err != nil {
+			t.Fatalf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := reader.Read(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+	}
+	reader.Close()
+
+	reader = NewPrefetchReader(ra, int64(buf.Len())-1, 4, WithInlineNames())
+	var err error
+	n := 0
+	for ; err == nil; n++ {
+		_, err = reader.Read()
+	}
+	if err != ErrNotEnoughBytes || n != len(blocks) {
+		t.Errorf("expected ErrNotEnoughBytes at block %d; got %v at block %d", len(blocks)-1, err, n-1)
+	}
+	reader.Close()
+
+	// Closing early stops the goroutine blocked on a full buffer.
+	reader = NewPrefetchReader(ra, int64(buf.Len()), 1, WithInlineNames())
+	reader.Read()
+	if err := reader.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := reader.Read(); err != ErrReaderClosed {
+		t.Errorf("expected ErrReaderClosed; got %v", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
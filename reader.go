@@ -0,0 +1,382 @@
+package byteblock
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// ByteBlockReader reads blocks from a stream specified at construction,
+// usually written by a ByteBlockWriter. Unlike ByteBlockSlicer, it
+// does not need the whole stream in memory, which suits pipes and
+// network connections.
+type ByteBlockReader struct {
+	reader       io.Reader
+	numBytesRead int64
+	blockPos     int64 // position of the header of the current block
+	numBlocks    int64 // index of the next block, see ReadInfo
+	err          error
+	header       []byte
+	frameHash    hash.Hash32  // checksum of the current block, see WithFrameChecksum
+	block        *blockReader // current block returned by NextReader, if not finished
+	count        int64        // see WithCountHeader, or -1 until read
+	opts         options
+}
+
+// NewByteBlockReader creates a new reader that reads blocks from r.
+func NewByteBlockReader(r io.Reader, opts ...Option) *ByteBlockReader {
+	o := newOptions(opts)
+	reader := &ByteBlockReader{reader: r, header: make([]byte, o.headerSize()), count: -1, opts: o}
+	if o.macKey != nil {
+		reader.reader = newMACReader(r, &reader.opts)
+	}
+	if o.frameChecksum {
+		reader.frameHash = crc32.New(castagnoliTable)
+	}
+	return reader
+}
+
+// Read reads the next data block into a newly allocated slice. It
+// returns io.EOF if the stream ends cleanly before the next block.
+func (r *ByteBlockReader) Read() (data []byte, err error) {
+	_, data, err = r.read()
+	return data, err
+}
+
+// read is like Read except that it also returns information about the
+// block.
+func (r *ByteBlockReader) read() (info BlockInfo, data []byte, err error) {
+	h, err := r.readHeader()
+	if err != nil {
+		return BlockInfo{}, nil, err
+	}
+	info = BlockInfo{
+		Index:      r.numBlocks,
+		Offset:     r.blockPos,
+		DataOffset: r.numBytesRead,
+		Padding:    h.padding,
+	}
+	if data, r.err = r.readData(h.length); r.err != nil {
+		return BlockInfo{}, nil, r.err
+	}
+	var sum uint32
+	if r.opts.checksums {
+		sum = crc32.Checksum(data, castagnoliTable)
+	}
+	if r.err = r.finishBlock(sum); r.err != nil {
+		return BlockInfo{}, nil, r.err
+	}
+	if r.opts.inlineNames {
+		if _, data, r.err = splitInlineName(data); r.err != nil {
+			return BlockInfo{}, nil, r.err
+		}
+	}
+	info.DataOffset += h.length - int64(len(data))
+	r.numBlocks++
+	if r.opts.transformsData() {
+		if data, err = r.opts.decodeData(h, data); err != nil {
+			return BlockInfo{}, nil, err
+		}
+	}
+	info.Length = int64(len(data))
+	return info, data, nil
+}
+
+// Drain skips all remaining blocks until the end of the stream without
+// keeping their data, and returns the number of blocks skipped. This
+// leaves the underlying reader at EOF. Reaching EOF is not an error.
+func (r *ByteBlockReader) Drain() (blocks int, err error) {
+	for {
+		h, err := r.readHeader()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return blocks, err
+		}
+		var sum uint32
+		if sum, r.err = r.discardData(h.length); r.err != nil {
+			return blocks, r.err
+		}
+		if r.err = r.finishBlock(sum); r.err != nil {
+			return blocks, r.err
+		}
+		r.numBlocks++
+		blocks++
+	}
+}
+
+// Advance reads and drops the next n bytes of the stream, such as to
+// skip to a block boundary known from a checkpoint. The position of
+// the reader, which matters for WithAbsoluteOffset and
+// WithAlignInHeader, accounts for the skipped bytes. The preamble of
+// WithMagicHeader and the count of WithCountHeader, if not read yet,
+// are read and checked as usual, and count toward n; ErrInvalidOffset
+// is returned if n ends within them. ErrNotEnoughBytes is returned if
+// the stream ends before n bytes.
+func (r *ByteBlockReader) Advance(n int64) error {
+	if r.err != nil {
+		return r.err
+	}
+	if n < 0 {
+		return ErrInvalidLength
+	}
+	if err := r.skipBlock(); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	start := r.numBytesRead
+	if err := r.readPrefix(); err == io.EOF {
+		r.err = ErrNotEnoughBytes
+		return r.err
+	} else if err != nil {
+		return err
+	}
+	if n -= r.numBytesRead - start; n < 0 {
+		r.err = ErrInvalidOffset
+		return r.err
+	}
+	r.err = r.discard(n)
+	return r.err
+}
+
+// readHeader reads the header of the next block and the padding after
+// it.
+func (r *ByteBlockReader) readHeader() (h header, err error) {
+	if r.err != nil {
+		return header{}, r.err
+	}
+	if err := r.skipBlock(); err != nil {
+		return header{}, err
+	}
+	if d := r.opts.readDeadline; d > 0 {
+		src := r.reader
+		if m, ok := src.(*macReader); ok {
+			src = m.r
+		}
+		if c, ok := src.(interface{ SetReadDeadline(time.Time) error }); ok {
+			if r.err = c.SetReadDeadline(time.Now().Add(d)); r.err != nil {
+				return header{}, r.err
+			}
+		}
+	}
+	if err := r.readPrefix(); err != nil {
+		return header{}, err
+	}
+	r.blockPos = r.numBytesRead
+	n, err := io.ReadFull(r.reader, r.header)
+	r.numBytesRead += int64(n)
+	if err == io.EOF {
+		// A clean end of stream is not sticky, so that a reader over a
+		// growing stream can be retried.
+		return header{}, io.EOF
+	}
+	if err != nil {
+		r.err = readError(err)
+		return header{}, r.err
+	}
+	if r.frameHash != nil {
+		r.frameHash.Reset()
+		r.frameHash.Write(r.header)
+	}
+	if h, r.err = r.opts.decodeHeader(r.header, r.blockPos); r.err != nil {
+		return header{}, r.err
+	}
+	if r.err = r.discard(h.padding); r.err != nil {
+		return header{}, r.err
+	}
+	if r.opts.blockKinds && h.kind() == KindEnd {
+		// The data of the end marker is at most an inline name.
+		var sum uint32
+		if sum, r.err = r.discardData(h.length); r.err == nil {
+			if r.err = r.finishBlock(sum); r.err == nil {
+				r.err = io.EOF
+			}
+		}
+		return header{}, r.err
+	}
+	return h, nil
+}
+
+// readPrefix reads what comes before the first block, unless already
+// read: the preamble of WithMagicHeader and the count header of
+// WithCountHeader.
+func (r *ByteBlockReader) readPrefix() error {
+	if r.opts.magicHeader && r.numBytesRead == 0 {
+		if err := r.readPreamble(); err != nil {
+			return err
+		}
+	}
+	if r.opts.countHeader && r.count < 0 {
+		var b [countHeaderSize]byte
+		n, err := io.ReadFull(r.reader, b[:])
+		r.numBytesRead += int64(n)
+		if err == io.EOF {
+			return io.EOF
+		}
+		if err != nil {
+			r.err = readError(err)
+			return r.err
+		}
+		r.count = readInt64(b[:])
+	}
+	return nil
+}
+
+// ReadCount returns the number of blocks written by
+// ByteBlockWriter.WriteCountHeader, reading it first if no block has
+// been read yet. The reader must have been created with
+// WithCountHeader; otherwise ErrCountHeaderDisabled is returned.
+func (r *ByteBlockReader) ReadCount() (int64, error) {
+	if !r.opts.countHeader {
+		return 0, ErrCountHeaderDisabled
+	}
+	if r.count < 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if err := r.readPrefix(); err != nil {
+			return 0, err
+		}
+	}
+	return r.count, nil
+}
+
+// readPreamble reads and checks the preamble at the start of the
+// stream, see WithMagicHeader.
+func (r *ByteBlockReader) readPreamble() error {
+	b := make([]byte, preambleSize)
+	n, err := io.ReadFull(r.reader, b)
+	r.numBytesRead += int64(n)
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		r.err = readError(err)
+		return r.err
+	}
+	r.err = r.opts.checkPreamble(b)
+	return r.err
+}
+
+// finishBlock reads whatever follows the data of the current block,
+// whose checksum is sum if WithChecksums is enabled.
+func (r *ByteBlockReader) finishBlock(sum uint32) error {
+	if stride := r.opts.stride; stride > 0 {
+		if r.numBytesRead-r.blockPos > stride-r.opts.trailerSize() {
+			return ErrBlockTooLarge
+		}
+		if err := r.discard(r.blockPos + stride - r.opts.trailerSize() - r.numBytesRead); err != nil {
+			return err
+		}
+	}
+	if r.opts.checksums {
+		var b [8]byte
+		if err := r.rawRead(b[:]); err != nil {
+			return err
+		}
+		if r.opts.readField(b[:]) != int64(sum) {
+			return ErrChecksumMismatch
+		}
+	}
+	if r.frameHash != nil {
+		sum := r.frameHash.Sum32()
+		var b [8]byte
+		if err := r.rawRead(b[:]); err != nil {
+			return err
+		}
+		if r.opts.readField(b[:]) != int64(sum) {
+			return ErrFrameChecksumMismatch
+		}
+	}
+	if r.opts.backLink {
+		var b [8]byte
+		if err := r.rawRead(b[:]); err != nil {
+			return err
+		}
+		if r.opts.readField(b[:]) != r.numBytesRead-r.blockPos {
+			return ErrInvalidBackLink
+		}
+	}
+	return nil
+}
+
+// rawRead fills data from the underlying reader.
+func (r *ByteBlockReader) rawRead(data []byte) error {
+	n, err := io.ReadFull(r.reader, data)
+	r.numBytesRead += int64(n)
+	if r.frameHash != nil {
+		r.frameHash.Write(data[:n])
+	}
+	return readError(err)
+}
+
+// readChunkSize is the number of bytes readData allocates upfront.
+const readChunkSize = 1 << 20
+
+// readData reads n bytes of block data into a new slice. As n comes
+// from a header that may be corrupted or crafted, the slice only grows
+// as the data arrives, doubling in size, rather than being allocated
+// upfront.
+func (r *ByteBlockReader) readData(n int64) ([]byte, error) {
+	data := make([]byte, 0, min(n, readChunkSize))
+	for int64(len(data)) < n {
+		chunk := int(min(n-int64(len(data)), int64(max(len(data), readChunkSize))))
+		data = slices.Grow(data, chunk)
+		if err := r.rawRead(data[len(data) : len(data)+chunk]); err != nil {
+			return nil, err
+		}
+		data = data[:len(data)+chunk]
+	}
+	return data, nil
+}
+
+// discard reads and drops n bytes from the underlying reader.
+func (r *ByteBlockReader) discard(n int64) error {
+	var dst io.Writer = io.Discard
+	if r.frameHash != nil {
+		dst = r.frameHash
+	}
+	m, err := io.CopyN(dst, r.reader, n)
+	r.numBytesRead += m
+	return readError(err)
+}
+
+// discardData reads and drops n bytes of block data, and returns their
+// checksum if WithChecksums is enabled.
+func (r *ByteBlockReader) discardData(n int64) (uint32, error) {
+	if !r.opts.checksums {
+		return 0, r.discard(n)
+	}
+	payloadHash := crc32.New(castagnoliTable)
+	var dst io.Writer = payloadHash
+	if r.frameHash != nil {
+		dst = io.MultiWriter(payloadHash, r.frameHash)
+	}
+	m, err := io.CopyN(dst, r.reader, n)
+	r.numBytesRead += m
+	return payloadHash.Sum32(), readError(err)
+}
+
+// readError translates an error from reading a part of a block. The
+// stream ending before the block does is reported as
+// ErrNotEnoughBytes, and an expired deadline additionally matches
+// ErrReadTimeout.
+func readError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrNotEnoughBytes
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrReadTimeout, err)
+	}
+	return err
+}
+
+var ErrReadTimeout = errors.New("timed out reading block")
@@ -0,0 +1,199 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	blocks := []string{"hello", "", "world", "!"}
+	ends := map[int]bool{}
+	for i, b := range blocks {
+		writer.WriteString(b, int64(8*i))
+		ends[buf.Len()] = true
+	}
+	reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()))
+	for _, expected := range blocks {
+		if data, err := reader.Read(); string(data) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, data, err)
+		}
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	for i := 1; i < buf.Len(); i++ {
+		reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()[:i]))
+		var err error
+		for err == nil {
+			_, err = reader.Read()
+		}
+		if ends[i] && err != io.EOF {
+			t.Errorf("truncated to %d bytes: expected io.EOF; got %v", i, err)
+		} else if !ends[i] && err != ErrNotEnoughBytes {
+			t.Errorf("truncated to %d bytes: expected ErrNotEnoughBytes; got %v", i, err)
+		}
+	}
+}
+
+func TestDrain(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for i := 0; i < 5; i++ {
+		writer.Write(bytes.Repeat([]byte{'x'}, i*10), 16)
+	}
+	src := bytes.NewReader(buf.Bytes())
+	reader := NewByteBlockReader(src)
+	if data, err := reader.Read(); len(data) != 0 || err != nil {
+		t.Errorf("expected empty block; got %q, %v", data, err)
+	}
+	if n, err := reader.Drain(); n != 4 || err != nil {
+		t.Errorf("expected 4 blocks drained; got %d, %v", n, err)
+	}
+	if src.Len() != 0 {
+		t.Errorf("expected source at EOF; %d bytes left", src.Len())
+	}
+	if n, err := reader.Drain(); n != 0 || err != nil {
+		t.Errorf("expected nothing left to drain; got %d, %v", n, err)
+	}
+
+	reader = NewByteBlockReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if n, err := reader.Drain(); n != 4 || err != ErrNotEnoughBytes {
+		t.Errorf("expected 4 blocks and ErrNotEnoughBytes; got %d, %v", n, err)
+	}
+}
+
+// stallingConn serves its data and then blocks until the read
+// deadline, like a connection to a stalled peer.
+type stallingConn struct {
+	data      []byte
+	deadline  time.Time
+	deadlines int
+}
+
+func (c *stallingConn) Read(p []byte) (int, error) {
+	if len(c.data) > 0 {
+		n := copy(p, c.data)
+		c.data = c.data[n:]
+		return n, nil
+	}
+	if c.deadline.IsZero() {
+		panic("read without deadline would block forever")
+	}
+	time.Sleep(time.Until(c.deadline))
+	return 0, os.ErrDeadlineExceeded
+}
+
+func (c *stallingConn) SetReadDeadline(t time.Time) error {
+	c.deadline = t
+	c.deadlines++
+	return nil
+}
+
+func TestAdvance(t *testing.T) {
+	var buf bytes.Buffer
+	// Absolute offsets check that the position accounts for the
+	// skipped bytes.
+	writer := NewByteBlockWriter(&buf, WithAbsoluteOffset())
+	writer.WriteString("hello", 8)
+	writer.WriteString("world", 16)
+	checkpoint := int64(buf.Len())
+	writer.WriteString("!", 32)
+
+	reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), WithAbsoluteOffset())
+	if err := reader.Advance(checkpoint); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, err := reader.Read(); string(data) != "!" || err != nil {
+		t.Errorf("expected %q; got %q, %v", "!", data, err)
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	reader = NewByteBlockReader(bytes.NewReader(buf.Bytes()))
+	if err := reader.Advance(int64(buf.Len()) + 1); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+
+	// The count header and the preamble are read along the way.
+	for _, opts := range [][]Option{
+		{WithCountHeader()},
+		{WithCountHeader(), WithMagicHeader()},
+	} {
+		buf.Reset()
+		writer = NewByteBlockWriter(&buf, opts...)
+		writer.WriteCountHeader(2)
+		writer.WriteString("hello", 8)
+		checkpoint = int64(buf.Len())
+		writer.WriteString("world", 8)
+		writer.Close()
+		reader = NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		if err := reader.Advance(checkpoint); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data, err := reader.Read(); string(data) != "world" || err != nil {
+			t.Errorf("expected %q; got %q, %v", "world", data, err)
+		}
+		if n, err := reader.ReadCount(); n != 2 || err != nil {
+			t.Errorf("expected a count of 2; got %d, %v", n, err)
+		}
+		reader = NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		if err := reader.Advance(countHeaderSize - 1); err != ErrInvalidOffset {
+			t.Errorf("expected ErrInvalidOffset; got %v", err)
+		}
+	}
+	// A bad preamble is caught rather than skipped.
+	reader = NewByteBlockReader(bytes.NewReader(buf.Bytes()[1:]), WithCountHeader(), WithMagicHeader())
+	if err := reader.Advance(checkpoint - 1); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("expected ErrBadMagic; got %v", err)
+	}
+}
+
+func TestReadDeadline(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.Write([]byte("hello"), 8)
+	writer.NewBlock(8, 5)
+	writer.Append([]byte("wor"))
+
+	conn := &stallingConn{data: buf.Bytes()}
+	reader := NewByteBlockReader(conn, WithReadDeadline(10*time.Millisecond))
+	if data, err := reader.Read(); string(data) != "hello" || err != nil {
+		t.Errorf("expected hello; got %q, %v", data, err)
+	}
+	start := time.Now()
+	_, err := reader.Read()
+	if !errors.Is(err, ErrReadTimeout) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected ErrReadTimeout and os.ErrDeadlineExceeded; got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("expected to wait for the deadline; waited %v", elapsed)
+	}
+	if conn.deadlines != 2 {
+		t.Errorf("expected a deadline per block; got %d", conn.deadlines)
+	}
+}
+
+func TestReadHugeLength(t *testing.T) {
+	// A crafted header claiming far more data than the stream holds
+	// must not make the readers allocate it upfront.
+	header := make([]byte, 16)
+	opts := newOptions(nil)
+	lengthPos, _ := opts.fieldPos()
+	fillInt64(1<<62, header[lengthPos:])
+	if _, err := NewByteBlockReader(bytes.NewReader(header)).Read(); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+	prefetch := NewPrefetchReader(bytes.NewReader(header), int64(len(header)), 1)
+	defer prefetch.Close()
+	if _, err := prefetch.Read(); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// ReadBlockRange reads length bytes starting at start within the data
+// of the block whose header begins at headerPos in ra. Only the header
+// and the requested range are read, which makes it suitable for
+// serving sub-ranges of big blocks. start and length must describe a
+// range within the block; otherwise ErrRangeOutOfBlock is returned.
+func ReadBlockRange(ra io.ReaderAt, headerPos, start, length int64) ([]byte, error) {
+	blockLength, dataPos, err := LengthAt(ra, headerPos)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || length < 0 || start > blockLength || length > blockLength-start {
+		return nil, ErrRangeOutOfBlock
+	}
+	data := make([]byte, length)
+	if err := readFullAt(ra, data, dataPos+start); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// LengthAt reads only the header of the block at headerPos in ra, and
+// returns the length of the block and the position of its data. This
+// allows planning reads or indexing a stream while touching nothing
+// but headers. With WithInlineNames, the data includes the inline
+// name.
+func LengthAt(ra io.ReaderAt, headerPos int64, opts ...Option) (length, dataPos int64, err error) {
+	o := newOptions(opts)
+	return o.lengthAt(ra, headerPos)
+}
+
+func (o *options) lengthAt(ra io.ReaderAt, headerPos int64) (length, dataPos int64, err error) {
+	var b [32]byte
+	if err := readFullAt(ra, b[:o.headerSize()], headerPos); err != nil {
+		return 0, 0, err
+	}
+	h, err := o.decodeHeader(b[:], headerPos)
+	if err != nil {
+		return 0, 0, err
+	}
+	dataPos = headerPos + o.headerSize()
+	if h.padding > math.MaxInt64-dataPos-h.length {
+		return 0, 0, ErrNotEnoughBytes
+	}
+	return h.length, dataPos + h.padding, nil
+}
+
+// ByteBlockReaderAt reads blocks at random from an io.ReaderAt, such
+// as an *os.File, without reading the whole stream. It is safe for
+// concurrent use if the io.ReaderAt is, except for ReadBlock and
+// GetByName.
+type ByteBlockReaderAt struct {
+	ra   io.ReaderAt
+	size int64
+	// offsets holds the header positions of the blocks found so far
+	// by ReadBlock.
+	offsets []int64
+	// indexLoaded tells whether offsets holds all blocks from the
+	// index footer, see WithIndexFooter.
+	indexLoaded bool
+	// names maps inline names to the header positions of the first
+	// blocks with them, once built by GetByName.
+	names map[string]int64
+	// preambleChecked tells whether the preamble has been checked, see
+	// WithMagicHeader.
+	preambleChecked bool
+	opts            options
+}
+
+// NewByteBlockReaderAt creates a reader of the blocks in the first
+// size bytes of ra.
+func NewByteBlockReaderAt(ra io.ReaderAt, size int64, opts ...Option) *ByteBlockReaderAt {
+	o := newOptions(opts)
+	if o.macKey != nil {
+		// Stop before the trailer of WithStreamMAC.
+		size = max(size-o.macTrailerSize(), 0)
+	}
+	return &ByteBlockReaderAt{ra: ra, size: size, offsets: []int64{o.preambleSize()}, opts: o}
+}
+
+// ReadBlockAt reads the data of the block whose header starts at
+// headerPos, such as a position recorded by
+// ByteBlockWriter.NextHeaderOffset, into a newly allocated slice. It
+// also returns the position of the next header. Only the block itself
+// is read. io.EOF is returned if headerPos is the end of the stream.
+func (r *ByteBlockReaderAt) ReadBlockAt(headerPos int64) (data []byte, next int64, err error) {
+	if headerPos == r.size {
+		return nil, 0, io.EOF
+	}
+	if next, err = r.nextHeader(headerPos); err != nil {
+		return nil, 0, err
+	}
+	frame := make([]byte, next-headerPos)
+	if err := readFullAt(r.ra, frame, headerPos); err != nil {
+		return nil, 0, err
+	}
+	slicer := &ByteBlockSlicer{data: frame, basePos: headerPos, prevLength: -1, blocksLeft: -1, opts: r.opts}
+	if data, err = slicer.Slice(); err != nil {
+		return nil, 0, err
+	}
+	return data, next, nil
+}
+
+// ReadBlock reads the data of block index into a newly allocated
+// slice. With WithFixedStride, the block is located directly; with
+// WithIndexFooter, the index is read once and used to locate blocks;
+// otherwise the headers of the blocks before it are read once, and
+// their positions are kept for later calls. ErrIndexOutOfRange is
+// returned if there is no such block.
+func (r *ByteBlockReaderAt) ReadBlock(index int64) ([]byte, error) {
+	headerPos, err := r.locate(index)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := r.ReadBlockAt(headerPos)
+	return data, err
+}
+
+// locate returns the position of the header of block index.
+func (r *ByteBlockReaderAt) locate(index int64) (headerPos int64, err error) {
+	if index < 0 {
+		return 0, ErrIndexOutOfRange
+	}
+	if r.opts.magicHeader && !r.preambleChecked {
+		b := make([]byte, preambleSize)
+		if err := readFullAt(r.ra, b, 0); err != nil && err != ErrNotEnoughBytes {
+			return 0, err
+		}
+		if err := r.opts.checkPreamble(b); err != nil {
+			return 0, err
+		}
+		r.preambleChecked = true
+	}
+	if r.opts.indexFooter {
+		if !r.indexLoaded {
+			indexPos, entries, err := r.opts.loadIndex(r.ra, r.size)
+			if err != nil {
+				return 0, err
+			}
+			r.offsets = r.offsets[:0]
+			for _, e := range entries {
+				r.offsets = append(r.offsets, e.Offset)
+			}
+			r.offsets = append(r.offsets, indexPos)
+			r.indexLoaded = true
+		}
+		if index >= int64(len(r.offsets))-1 {
+			return 0, ErrIndexOutOfRange
+		}
+		return r.offsets[index], nil
+	}
+	if stride := r.opts.stride; stride > 0 {
+		start := r.opts.preambleSize()
+		if index >= (r.size-start)/stride {
+			return 0, ErrIndexOutOfRange
+		}
+		return start + index*stride, nil
+	}
+	for int64(len(r.offsets)) <= index {
+		last := r.offsets[len(r.offsets)-1]
+		if last == r.size {
+			return 0, ErrIndexOutOfRange
+		}
+		next, err := r.nextHeader(last)
+		if err != nil {
+			return 0, err
+		}
+		r.offsets = append(r.offsets, next)
+	}
+	if r.offsets[index] == r.size {
+		return 0, ErrIndexOutOfRange
+	}
+	return r.offsets[index], nil
+}
+
+// nextHeader returns the position of the header after the block whose
+// header starts at headerPos, reading only the header.
+func (r *ByteBlockReaderAt) nextHeader(headerPos int64) (int64, error) {
+	if headerPos < 0 || headerPos > r.size {
+		return 0, ErrInvalidOffset
+	}
+	if stride := r.opts.stride; stride > 0 {
+		if stride > r.size-headerPos {
+			return 0, ErrNotEnoughBytes
+		}
+		return headerPos + stride, nil
+	}
+	if r.size-headerPos < r.opts.headerSize() {
+		return 0, ErrNotEnoughBytes
+	}
+	length, dataPos, err := r.opts.lengthAt(r.ra, headerPos)
+	if err != nil {
+		return 0, err
+	}
+	if length > r.size-dataPos || r.opts.trailerSize() > r.size-dataPos-length {
+		return 0, ErrNotEnoughBytes
+	}
+	return dataPos + length + r.opts.trailerSize(), nil
+}
+
+var ErrRangeOutOfBlock = errors.New("range out of block")
+
+// readFullAt fills data from ra starting at pos. A short read caused
+// by reaching the end of ra is reported as ErrNotEnoughBytes.
+func readFullAt(ra io.ReaderAt, data []byte, pos int64) error {
+	n, err := ra.ReadAt(data, pos)
+	if n == len(data) {
+		return nil
+	}
+	if err == io.EOF {
+		return ErrNotEnoughBytes
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestReadBlockRange(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.Write([]byte("hello"), 0)
+	headerPos := int64(buf.Len())
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := writer.Write(data, 64); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ra := bytes.NewReader(buf.Bytes())
+	got, err := ReadBlockRange(ra, headerPos, 450, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, data[450:550]) {
+		t.Errorf("got %v", got)
+	}
+
+	for _, i := range []struct {
+		Start, Length int64
+	}{
+		{-1, 10}, {0, -1}, {0, 1001}, {900, 101}, {1001, 0},
+	} {
+		if _, err := ReadBlockRange(ra, headerPos, i.Start, i.Length); err != ErrRangeOutOfBlock {
+			t.Errorf("case %+v: expected ErrRangeOutOfBlock; got %v", i, err)
+		}
+	}
+
+	if _, err := ReadBlockRange(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), headerPos, 900, 100); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
+
+func TestLengthAt(t *testing.T) {
+	opts := []Option{WithAlignInHeader(), WithBlockVersions(), WithFrameChecksum()}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, opts...)
+	for i, length := range []int{5, 0, 100, 7} {
+		writer.Write(make([]byte, length), int64(8<<i))
+	}
+	m, err := Manifest(buf.Bytes(), opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ra := bytes.NewReader(buf.Bytes())
+	var headerPos int64
+	for _, block := range m.Blocks {
+		if headerPos != block.Offset {
+			t.Fatalf("expected header at %d; got %d", block.Offset, headerPos)
+		}
+		length, dataPos, err := LengthAt(ra, headerPos, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if length != block.Length || dataPos != block.DataOffset {
+			t.Errorf("expected %d, %d; got %d, %d", block.Length, block.DataOffset, length, dataPos)
+		}
+		headerPos = dataPos + length + 8
+	}
+	if headerPos != m.Size {
+		t.Errorf("expected to end at %d; got %d", m.Size, headerPos)
+	}
+
+	if _, _, err := LengthAt(ra, m.Size-8, opts...); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
+
+func TestByteBlockReaderAt(t *testing.T) {
+	blocks := []string{"hello", "", "wonderful", "world", "!"}
+	for _, opts := range [][]Option{
+		nil,
+		{WithAbsoluteOffset(), WithInlineNames()},
+		{WithAlignInHeader(), WithFrameChecksum(), WithBackLink()},
+		{WithFixedStride(128), WithBlockVersions()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		var offsets []int64
+		for i, b := range blocks {
+			offsets = append(offsets, writer.NextHeaderOffset())
+			if err := writer.WriteString(b, int64(4<<i)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		offsets = append(offsets, int64(buf.Len()))
+		reader := NewByteBlockReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), opts...)
+
+		for _, i := range []int{3, 0, 4, 1, 2} {
+			if data, err := reader.ReadBlock(int64(i)); string(data) != blocks[i] || err != nil {
+				t.Errorf("block %d: expected %q; got %q, %v", i, blocks[i], data, err)
+			}
+			data, next, err := reader.ReadBlockAt(offsets[i])
+			if string(data) != blocks[i] || err != nil {
+				t.Errorf("block at %d: expected %q; got %q, %v", offsets[i], blocks[i], data, err)
+			}
+			if next != offsets[i+1] {
+				t.Errorf("block at %d: expected next at %d; got %d", offsets[i], offsets[i+1], next)
+			}
+		}
+		if _, err := reader.ReadBlock(int64(len(blocks))); err != ErrIndexOutOfRange {
+			t.Errorf("expected ErrIndexOutOfRange; got %v", err)
+		}
+		if _, _, err := reader.ReadBlockAt(int64(buf.Len())); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+
+		truncated := NewByteBlockReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len())-1, opts...)
+		if _, _, err := truncated.ReadBlockAt(offsets[len(blocks)-1]); err != ErrNotEnoughBytes {
+			t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+		}
+	}
+}
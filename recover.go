@@ -0,0 +1,153 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BlockError records an error encountered while slicing a block.
+type BlockError struct {
+	// Index is the index of the block in the stream, counting each
+	// earlier error as one lost block.
+	Index int
+	// Offset is the position of the block header in the stream.
+	Offset int64
+	Err    error
+}
+
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("block %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
+
+// SliceAllBestEffort slices as many blocks as possible out of a
+// possibly damaged stream. Whenever a block cannot be sliced, the
+// error is recorded as a *BlockError and slicing resumes at the first
+// later position at which a block slices cleanly and is followed by
+// either the end of data or the header of a block that fits in data,
+// so that the blocks between damaged parts are recovered. This is a
+// heuristic: a damaged stream may hide or fake blocks.
+func SliceAllBestEffort(data []byte, opts ...Option) (blocks [][]byte, errs []error) {
+	start := NewByteBlockSlicer(data, opts...)
+	if start.err != nil {
+		// Nothing can be sliced, such as without the right preamble.
+		return nil, []error{&BlockError{Err: start.err}}
+	}
+	slicer := start.at(0)
+	for {
+		offset := slicer.numBytesSliced
+		block, err := slicer.Slice()
+		if err == io.EOF {
+			return blocks, errs
+		}
+		if err == nil {
+			blocks = append(blocks, block)
+			continue
+		}
+		errs = append(errs, &BlockError{Index: len(blocks) + len(errs), Offset: slicer.basePos + offset, Err: err})
+		slicer = start.at(resyncOffset(start, offset+1))
+	}
+}
+
+// at returns a new slicer over the same data as r, positioned at pos.
+func (r *ByteBlockSlicer) at(pos int64) *ByteBlockSlicer {
+	return &ByteBlockSlicer{data: r.data, basePos: r.basePos, numBytesSliced: pos, prevLength: -1, blocksLeft: -1, opts: r.opts}
+}
+
+// resyncOffset returns the first position from pos on, relative to the
+// data of start, at which a block slices cleanly and is followed by the
+// end of the data or by the header of a block that fits in it, or the
+// end of the data if there is none.
+func resyncOffset(start *ByteBlockSlicer, pos int64) int64 {
+	for ; pos < int64(len(start.data)); pos++ {
+		slicer := start.at(pos)
+		if _, err := slicer.Slice(); err != nil {
+			continue
+		}
+		if _, _, _, err := slicer.sliceBlock(false); err == nil || err == io.EOF {
+			return pos
+		}
+	}
+	return int64(len(start.data))
+}
+
+// RecoveringReader reads blocks at random from an io.ReaderAt like
+// ByteBlockReaderAt, for streams written with WithSyncMarkers. Whenever
+// a block cannot be read, it records the error and resumes at the next
+// sync marker, salvaging the blocks that follow a damaged part of the
+// stream.
+type RecoveringReader struct {
+	reader *ByteBlockReaderAt
+	pos    int64 // position of the next header
+	index  int   // index of the next block, counting lost blocks
+	errs   []error
+}
+
+// NewRecoveringReader creates a reader of the blocks in the first size
+// bytes of ra. The options must include WithSyncMarkers; otherwise
+// every Read returns ErrSyncMarkersDisabled.
+func NewRecoveringReader(ra io.ReaderAt, size int64, opts ...Option) *RecoveringReader {
+	reader := NewByteBlockReaderAt(ra, size, opts...)
+	return &RecoveringReader{reader: reader, pos: reader.opts.preambleSize()}
+}
+
+// Read reads the data of the next block that can be read into a newly
+// allocated slice, skipping damaged parts of the stream. It returns
+// io.EOF at the end of the stream.
+func (r *RecoveringReader) Read() ([]byte, error) {
+	if !r.reader.opts.syncMarkers {
+		return nil, ErrSyncMarkersDisabled
+	}
+	for r.pos < r.reader.size {
+		data, next, err := r.reader.ReadBlockAt(r.pos)
+		if err == nil {
+			r.pos = next
+			r.index++
+			return data, nil
+		}
+		r.errs = append(r.errs, &BlockError{Index: r.index, Offset: r.pos, Err: err})
+		r.index++
+		if r.pos, err = r.nextMarker(r.pos + 1); err != nil {
+			return nil, err
+		}
+	}
+	return nil, io.EOF
+}
+
+// Errors returns the errors of the damaged parts of the stream skipped
+// so far, each as a *BlockError.
+func (r *RecoveringReader) Errors() []error {
+	return r.errs
+}
+
+// syncScanSize is the number of bytes read at a time when looking for
+// a sync marker.
+const syncScanSize = 64 << 10
+
+// nextMarker returns the position of the first sync marker from pos
+// on, or the end of the stream if there is none.
+func (r *RecoveringReader) nextMarker(pos int64) (int64, error) {
+	buf := make([]byte, syncScanSize)
+	for pos < r.reader.size {
+		n := min(int64(len(buf)), r.reader.size-pos)
+		if err := readFullAt(r.reader.ra, buf[:n], pos); err != nil {
+			return 0, err
+		}
+		if i := bytes.Index(buf[:n], syncMarker[:]); i >= 0 {
+			return pos + int64(i), nil
+		}
+		if pos+n == r.reader.size {
+			break
+		}
+		// A marker may straddle the end of the buffer.
+		pos += n - int64(len(syncMarker)) + 1
+	}
+	return r.reader.size, nil
+}
+
+var ErrSyncMarkersDisabled = errors.New("sync markers are not enabled")
@@ -0,0 +1,142 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSliceAllBestEffort(t *testing.T) {
+	blocks := []string{"hello", "world", "corrupted", "foo", "bar"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	var corruptAt int64
+	for i, b := range blocks {
+		if i == 2 {
+			corruptAt = writer.NextHeaderOffset()
+		}
+		writer.WriteString(b, 8)
+	}
+
+	recovered, errs := SliceAllBestEffort(buf.Bytes())
+	if len(errs) != 0 || len(recovered) != len(blocks) {
+		t.Errorf("intact stream: expected %d blocks; got %d, %v", len(blocks), len(recovered), errs)
+	}
+
+	data := append([]byte(nil), buf.Bytes()...)
+	fillInt64(1<<40, data[corruptAt:])
+	recovered, errs = SliceAllBestEffort(data)
+	var got []string
+	for _, b := range recovered {
+		got = append(got, string(b))
+	}
+	if expected := []string{"hello", "world", "foo", "bar"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error; got %v", errs)
+	}
+	var blockErr *BlockError
+	if !errors.As(errs[0], &blockErr) || blockErr.Index != 2 || blockErr.Offset != corruptAt {
+		t.Errorf("expected error for block 2 at %d; got %v", corruptAt, errs[0])
+	}
+	if !errors.Is(errs[0], ErrNotEnoughBytes) {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", errs[0])
+	}
+}
+
+func TestSliceAllBestEffortSeveral(t *testing.T) {
+	// Blocks 3 and 7 are damaged, so the blocks in between must be
+	// recovered too.
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	var corruptAt []int64
+	var expected []string
+	for i := 0; i < 10; i++ {
+		block := fmt.Sprint("block ", i)
+		if i == 3 || i == 7 {
+			corruptAt = append(corruptAt, writer.NextHeaderOffset())
+		} else {
+			expected = append(expected, block)
+		}
+		writer.WriteString(block, 8)
+	}
+	data := buf.Bytes()
+	for _, offset := range corruptAt {
+		fillInt64(1<<40, data[offset:])
+	}
+	recovered, errs := SliceAllBestEffort(data)
+	var got []string
+	for _, b := range recovered {
+		got = append(got, string(b))
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+	if len(errs) != len(corruptAt) {
+		t.Fatalf("expected %d errors; got %v", len(corruptAt), errs)
+	}
+	for i, index := range []int{3, 7} {
+		var blockErr *BlockError
+		if !errors.As(errs[i], &blockErr) || blockErr.Index != index || blockErr.Offset != corruptAt[i] {
+			t.Errorf("expected error for block %d at %d; got %v", index, corruptAt[i], errs[i])
+		}
+	}
+}
+
+func TestRecoveringReader(t *testing.T) {
+	opts := []Option{WithSyncMarkers(), WithChecksums(), WithMagicHeader()}
+	blocks := []string{"zero", "one", "two", "three", "four"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, opts...)
+	var offsets []int64
+	for _, b := range blocks {
+		offsets = append(offsets, writer.NextHeaderOffset())
+		writer.WriteString(b, 8)
+	}
+	data := buf.Bytes()
+	fillInt64(1<<40, data[offsets[1]+8:])             // length of block 1
+	data[offsets[4]-writer.opts.trailerSize()-1] ^= 1 // data of block 3
+
+	reader := NewRecoveringReader(bytes.NewReader(data), int64(len(data)), opts...)
+	var got []string
+	for {
+		block, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, string(block))
+	}
+	if expected := []string{"zero", "two", "four"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+	errs := reader.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors; got %v", errs)
+	}
+	for i, e := range []struct {
+		Index int
+		Err   error
+	}{{1, ErrNotEnoughBytes}, {3, ErrChecksumMismatch}} {
+		var blockErr *BlockError
+		if !errors.As(errs[i], &blockErr) || blockErr.Index != e.Index || blockErr.Offset != offsets[e.Index] || !errors.Is(errs[i], e.Err) {
+			t.Errorf("expected %v at block %d; got %v", e.Err, e.Index, errs[i])
+		}
+	}
+
+	// The sync marker is checked by all readers.
+	data[offsets[2]] ^= 1
+	if _, err := NewByteBlockSlicer(data[offsets[2]:], WithSyncMarkers(), WithChecksums()).Slice(); !errors.Is(err, ErrInvalidSyncMarker) {
+		t.Errorf("expected ErrInvalidSyncMarker; got %v", err)
+	}
+
+	if _, err := NewRecoveringReader(bytes.NewReader(data), int64(len(data))).Read(); err != ErrSyncMarkersDisabled {
+		t.Errorf("expected ErrSyncMarkersDisabled; got %v", err)
+	}
+}
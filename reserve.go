@@ -0,0 +1,36 @@
+package byteblock
+
+import "errors"
+
+// ReserveBlock writes a block with the given alignment whose length
+// bytes of data are all zeros, and returns the position of its data
+// relative to the start of the stream, as NextHeaderOffset does. The
+// data can then be filled in later through an io.WriterAt such as the
+// *os.File being written, so that a file is laid out first and its
+// sections are filled in any order, possibly in parallel. As the data
+// is not known when the block is written, blocks cannot be reserved
+// with WithChecksums, WithFrameChecksum, WithCodec, WithEncryption or
+// WithStreamMAC, and ErrReserveUnsupported is returned with those.
+func (w *ByteBlockWriter) ReserveBlock(align, length int64) (dataPos int64, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.opts.checksums || w.opts.frameChecksum || w.opts.transformsData() || w.opts.macKey != nil {
+		return 0, ErrReserveUnsupported
+	}
+	if err := w.newBlock(align, length, blockMeta{}); err != nil {
+		return 0, err
+	}
+	dataPos = w.numBytesWritten
+	for {
+		chunk := zeros[:min(length, int64(len(zeros)))]
+		if err := w.writeData(chunk); err != nil {
+			return 0, err
+		}
+		if length -= int64(len(chunk)); length == 0 {
+			return dataPos, nil
+		}
+	}
+}
+
+var ErrReserveUnsupported = errors.New("blocks cannot be reserved with the options")
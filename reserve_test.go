@@ -0,0 +1,67 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReserveBlock(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithInlineNames(), WithBackLink(), WithMagicHeader()},
+		{WithFixedStride(64 << 10)},
+	} {
+		f, err := os.Create(filepath.Join(t.TempDir(), "blocks"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		writer := NewByteBlockWriter(f, opts...)
+		first, err := writer.ReserveBlock(16, 5)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		writer.WriteString("middle", 8)
+		// Bigger than the chunks of zeros.
+		big := bytes.Repeat([]byte("x"), 10000)
+		second, err := writer.ReserveBlock(4096, int64(len(big)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if second%4096 != 0 {
+			t.Errorf("expected aligned data; got %d", second)
+		}
+		if _, err := writer.ReserveBlock(8, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Fill in the reverse order.
+		f.WriteAt(big, second)
+		f.WriteAt([]byte("hello"), first)
+
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		slicer := NewByteBlockSlicer(data, opts...)
+		for _, expected := range []string{"hello", "middle", string(big), ""} {
+			if block, err := slicer.Slice(); string(block) != expected || err != nil {
+				t.Errorf("expected %d bytes; got %d, %v", len(expected), len(block), err)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+	}
+
+	for _, opt := range []Option{WithChecksums(), WithFrameChecksum(), WithCodec(CodecGzip)} {
+		if _, err := NewByteBlockWriter(io.Discard, opt).ReserveBlock(8, 4); err != ErrReserveUnsupported {
+			t.Errorf("expected ErrReserveUnsupported; got %v", err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package byteblock
+
+import "io"
+
+// RollingWriter writes blocks to a series of segments, such as log
+// files, starting a new segment once the current one has reached a
+// size or a number of blocks, so that long-running producers get
+// bounded files without bookkeeping. Each segment is a complete stream
+// written with the options of the RollingWriter. Blocks are never
+// split across segments, so a segment exceeds the size limit by at
+// most its last block.
+type RollingWriter struct {
+	open      func(segment int) (io.WriteCloser, error)
+	maxSize   int64
+	maxBlocks int64
+	writer    *ByteBlockWriter // current segment, if any
+	file      io.WriteCloser   // of the current segment
+	segment   int              // index of the next segment
+	err       error
+	opts      []Option
+}
+
+// NewRollingWriter creates a RollingWriter that calls open to open
+// each segment in turn, from index 0, and starts a new segment before
+// the next block once the current one holds at least maxSize bytes or
+// maxBlocks blocks. A limit that is not positive does not apply. The
+// first segment is only opened by the first block.
+func NewRollingWriter(open func(segment int) (io.WriteCloser, error), maxSize, maxBlocks int64, opts ...Option) *RollingWriter {
+	return &RollingWriter{open: open, maxSize: maxSize, maxBlocks: maxBlocks, opts: opts}
+}
+
+// Write writes data as a block with the given alignment to the current
+// segment, starting a new segment first if needed.
+func (w *RollingWriter) Write(data []byte, align int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.writer == nil || w.full() {
+		if w.err = w.roll(); w.err != nil {
+			return w.err
+		}
+	}
+	w.err = w.writer.Write(data, align)
+	return w.err
+}
+
+// WriteString is like Write except that it takes a string.
+func (w *RollingWriter) WriteString(data string, align int64) error {
+	return w.Write(stringBytes(data), align)
+}
+
+// Segment returns the index of the current segment, or -1 if no
+// segment has been opened yet.
+func (w *RollingWriter) Segment() int {
+	return w.segment - 1
+}
+
+// full tells whether the current segment has reached a limit.
+func (w *RollingWriter) full() bool {
+	return w.maxSize > 0 && w.writer.numBytesWritten >= w.maxSize ||
+		w.maxBlocks > 0 && w.writer.numBlocks >= w.maxBlocks
+}
+
+// roll closes the current segment, if any, and opens the next one.
+func (w *RollingWriter) roll() error {
+	if err := w.closeSegment(); err != nil {
+		return err
+	}
+	f, err := w.open(w.segment)
+	if err != nil {
+		return err
+	}
+	w.segment++
+	w.writer, w.file = NewByteBlockWriter(f, w.opts...), f
+	w.writer.closer = f
+	return w.writer.err
+}
+
+// closeSegment closes the current segment, if any, and its file, which
+// ByteBlockWriter.Close leaves open if the stream fails to finish.
+func (w *RollingWriter) closeSegment() error {
+	if w.writer == nil {
+		return nil
+	}
+	writer, f := w.writer, w.file
+	w.writer, w.file = nil, nil
+	if err := writer.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return nil
+}
+
+// Close closes the current segment, and returns the first error of
+// the RollingWriter, if any. The file of the segment is closed even
+// then. Writing after Close fails with ErrWriterClosed.
+func (w *RollingWriter) Close() error {
+	if w.err == ErrWriterClosed {
+		return w.err
+	}
+	err := w.closeSegment()
+	if w.err != nil {
+		err = w.err
+	}
+	w.err = ErrWriterClosed
+	return err
+}
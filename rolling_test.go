@@ -0,0 +1,126 @@
+package byteblock
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollingWriter(t *testing.T) {
+	for _, i := range []struct {
+		MaxSize, MaxBlocks int64
+		Blocks             []int64 // number of blocks of each segment
+	}{
+		{0, 0, []int64{10}},
+		{0, 3, []int64{3, 3, 3, 1}},
+		{100, 0, []int64{4, 4, 2}},
+		{100, 3, []int64{3, 3, 3, 1}},
+	} {
+		dir := t.TempDir()
+		open := func(segment int) (io.WriteCloser, error) {
+			return os.Create(filepath.Join(dir, fmt.Sprint(segment)))
+		}
+		writer := NewRollingWriter(open, i.MaxSize, i.MaxBlocks, WithMagicHeader())
+		if s := writer.Segment(); s != -1 {
+			t.Errorf("expected no segment; got %d", s)
+		}
+		for j := 0; j < 10; j++ {
+			if err := writer.WriteString(fmt.Sprint("block ", j), 8); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := writer.WriteString("late", 8); err != ErrWriterClosed {
+			t.Errorf("expected ErrWriterClosed; got %v", err)
+		}
+		if s := writer.Segment(); s != len(i.Blocks)-1 {
+			t.Errorf("expected segment %d; got %d", len(i.Blocks)-1, s)
+		}
+
+		next := 0
+		for s, n := range i.Blocks {
+			data, err := os.ReadFile(filepath.Join(dir, fmt.Sprint(s)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			slicer := NewByteBlockSlicer(data, WithMagicHeader())
+			for j := int64(0); j < n; j++ {
+				expected := fmt.Sprint("block ", next)
+				if block, err := slicer.Slice(); string(block) != expected || err != nil {
+					t.Errorf("%+v: segment %d: expected %q; got %q, %v", i, s, expected, block, err)
+				}
+				next++
+			}
+			if _, err := slicer.Slice(); err != io.EOF {
+				t.Errorf("%+v: segment %d: expected io.EOF; got %v", i, s, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, fmt.Sprint(len(i.Blocks)))); !os.IsNotExist(err) {
+			t.Errorf("%+v: expected no more segments; got %v", i, err)
+		}
+	}
+}
+
+// segmentFile is a segment that fails writes past limit bytes, if
+// positive, and records whether it was closed.
+type segmentFile struct {
+	n, limit int
+	closed   bool
+}
+
+func (f *segmentFile) Write(p []byte) (int, error) {
+	if f.limit > 0 && f.n+len(p) > f.limit {
+		return 0, io.ErrShortWrite
+	}
+	f.n += len(p)
+	return len(p), nil
+}
+
+func (f *segmentFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestRollingWriterFailure(t *testing.T) {
+	var files []*segmentFile
+	open := func(limit int) func(int) (io.WriteCloser, error) {
+		return func(int) (io.WriteCloser, error) {
+			files = append(files, &segmentFile{limit: limit})
+			return files[len(files)-1], nil
+		}
+	}
+
+	// A failed block leaves the segment unfinished, yet Close closes it.
+	writer := NewRollingWriter(open(0), 0, 0, WithFixedStride(64))
+	writer.WriteString("fits", 8)
+	if err := writer.Write(make([]byte, 100), 8); err != ErrBlockTooLarge {
+		t.Errorf("expected ErrBlockTooLarge; got %v", err)
+	}
+	if err := writer.Close(); err != ErrBlockTooLarge {
+		t.Errorf("expected ErrBlockTooLarge; got %v", err)
+	}
+	if !files[0].closed {
+		t.Errorf("expected the segment to be closed")
+	}
+	if err := writer.Close(); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed; got %v", err)
+	}
+
+	// So does rolling over when the index footer cannot be written.
+	files = nil
+	writer = NewRollingWriter(open(32), 0, 1, WithIndexFooter())
+	writer.WriteString("fits", 8)
+	if err := writer.WriteString("next", 8); err != io.ErrShortWrite {
+		t.Errorf("expected io.ErrShortWrite; got %v", err)
+	}
+	if len(files) != 1 || !files[0].closed {
+		t.Errorf("expected the segment to be closed")
+	}
+	if err := writer.Close(); err != io.ErrShortWrite {
+		t.Errorf("expected io.ErrShortWrite; got %v", err)
+	}
+}
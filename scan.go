@@ -0,0 +1,131 @@
+package byteblock
+
+import (
+	"errors"
+	"hash"
+	"io"
+	"sort"
+)
+
+// Flatten slices all blocks out of data and concatenates them into a
+// single payload slice, without headers and padding. offsets[i] holds
+// the start and the length of block i within payload.
+func Flatten(data []byte, opts ...Option) (payload []byte, offsets [][2]int64, err error) {
+	var blocks [][]byte
+	size := 0
+	slicer := NewByteBlockSlicer(data, opts...)
+	for {
+		block, err := slicer.Slice()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, block)
+		size += len(block)
+	}
+	payload = make([]byte, 0, size)
+	offsets = make([][2]int64, len(blocks))
+	for i, block := range blocks {
+		offsets[i] = [2]int64{int64(len(payload)), int64(len(block))}
+		payload = append(payload, block...)
+	}
+	return payload, offsets, nil
+}
+
+// SizeHistogram counts the blocks in data by length. buckets holds
+// the inclusive upper bounds of the buckets in increasing order:
+// counts[i] is the number of blocks whose length is at most
+// buckets[i] but greater than buckets[i-1]. The extra last element of
+// counts is the number of blocks longer than all bounds.
+func SizeHistogram(data []byte, buckets []int64, opts ...Option) (counts []int64, err error) {
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return nil, ErrInvalidBuckets
+		}
+	}
+	counts = make([]int64, len(buckets)+1)
+	slicer := NewByteBlockSlicer(data, opts...)
+	for {
+		block, err := slicer.Slice()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		length := int64(len(block))
+		counts[sort.Search(len(buckets), func(i int) bool { return buckets[i] >= length })]++
+	}
+}
+
+// ContentHash feeds the blocks in data to h in order and returns the
+// resulting digest. Only the length and the data of each block are
+// hashed, not headers and padding, so the digest identifies the
+// content regardless of alignment and header options. The lengths keep
+// differently split payloads apart.
+func ContentHash(data []byte, h hash.Hash, opts ...Option) ([]byte, error) {
+	var stub [8]byte
+	slicer := NewByteBlockSlicer(data, opts...)
+	for {
+		block, err := slicer.Slice()
+		if err == io.EOF {
+			return h.Sum(nil), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fillInt64(int64(len(block)), stub[:])
+		h.Write(stub[:])
+		h.Write(block)
+	}
+}
+
+// Count returns the number of blocks in data, reading only headers and
+// trailers as ByteBlockSlicer.Skip does.
+func Count(data []byte, opts ...Option) (int64, error) {
+	slicer := NewByteBlockSlicer(data, opts...)
+	var n int64
+	for {
+		err := slicer.Skip()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		n++
+	}
+}
+
+// Offsets returns the position in data and the length of the data of
+// each block, as offsets[i] = {position, length}, reading only headers,
+// trailers and inline names as ByteBlockSlicer.Skip does. The lengths
+// are those stored, before any decompression with WithCodec.
+func Offsets(data []byte, opts ...Option) (offsets [][2]int64, err error) {
+	slicer := NewByteBlockSlicer(data, opts...)
+	offsets = [][2]int64{}
+	for {
+		headerPos := slicer.basePos + slicer.numBytesSliced
+		h, _, block, err := slicer.sliceBlock(false)
+		if err == io.EOF {
+			return offsets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		dataPos := headerPos + slicer.opts.headerSize() + h.padding
+		if slicer.opts.inlineNames {
+			var rest []byte
+			if _, rest, err = splitInlineName(block); err != nil {
+				return nil, err
+			}
+			dataPos += int64(len(block) - len(rest))
+			block = rest
+		}
+		offsets = append(offsets, [2]int64{dataPos, int64(len(block))})
+	}
+}
+
+var ErrInvalidBuckets = errors.New("bucket bounds are not increasing")
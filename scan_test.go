@@ -0,0 +1,136 @@
+package byteblock
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	blocks := []string{"hello", "", "world", "!"}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, b := range blocks {
+		writer.WriteString(b, 16)
+	}
+	payload, offsets, err := Flatten(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(payload) != "helloworld!" {
+		t.Errorf("got payload %q", payload)
+	}
+	if len(offsets) != len(blocks) {
+		t.Fatalf("expected %d offsets; got %d", len(blocks), len(offsets))
+	}
+	for i, b := range blocks {
+		off := offsets[i]
+		if got := string(payload[off[0] : off[0]+off[1]]); got != b {
+			t.Errorf("block %d: expected %q; got %q", i, b, got)
+		}
+	}
+
+	if _, _, err := Flatten(buf.Bytes()[:buf.Len()-1]); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
+
+func TestSizeHistogram(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, length := range []int{0, 1, 10, 11, 100, 5, 1000, 101, 4096} {
+		writer.Write(make([]byte, length), 8)
+	}
+	counts, err := SizeHistogram(buf.Bytes(), []int64{0, 10, 100, 1000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []int64{1, 3, 2, 2, 1}; !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %v; got %v", expected, counts)
+	}
+
+	if counts, err := SizeHistogram(buf.Bytes(), nil); err != nil || !reflect.DeepEqual(counts, []int64{9}) {
+		t.Errorf("expected [9]; got %v, %v", counts, err)
+	}
+	if _, err := SizeHistogram(buf.Bytes(), []int64{10, 10}); err != ErrInvalidBuckets {
+		t.Errorf("expected ErrInvalidBuckets; got %v", err)
+	}
+	if _, err := SizeHistogram(buf.Bytes()[:buf.Len()-1], []int64{10}); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	hash := func(blocks []string, align int64, opts ...Option) []byte {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, b := range blocks {
+			writer.WriteString(b, align)
+		}
+		digest, err := ContentHash(buf.Bytes(), sha256.New(), opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return digest
+	}
+	blocks := []string{"hello", "", "world"}
+	digest := hash(blocks, 8)
+	if other := hash(blocks, 4096); !bytes.Equal(digest, other) {
+		t.Errorf("expected equal digests for different alignments")
+	}
+	if other := hash(blocks, 1, WithAlignInHeader(), WithBlockVersions()); !bytes.Equal(digest, other) {
+		t.Errorf("expected equal digests for different header options")
+	}
+	if other := hash([]string{"hellow", "", "orld"}, 8); bytes.Equal(digest, other) {
+		t.Errorf("expected different digests for different splits")
+	}
+	if other := hash([]string{"hello", "world"}, 8); bytes.Equal(digest, other) {
+		t.Errorf("expected different digests without the empty block")
+	}
+
+	if _, err := ContentHash([]byte{1}, sha256.New()); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
+
+func TestCountAndOffsets(t *testing.T) {
+	blocks := []string{"hello", "", "world", "!"}
+	for _, opts := range [][]Option{
+		nil,
+		{WithInlineNames(), WithChecksums(), WithMagicHeader()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, b := range blocks {
+			writer.WriteString(b, 16)
+		}
+		data := buf.Bytes()
+		if n, err := Count(data, opts...); n != int64(len(blocks)) || err != nil {
+			t.Errorf("expected %d blocks; got %d, %v", len(blocks), n, err)
+		}
+		offsets, err := Offsets(data, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(offsets) != len(blocks) {
+			t.Fatalf("expected %d offsets; got %d", len(blocks), len(offsets))
+		}
+		for i, b := range blocks {
+			off := offsets[i]
+			if got := string(data[off[0] : off[0]+off[1]]); got != b || off[0]%16 != 0 {
+				t.Errorf("block %d: expected %q; got %q at %d", i, b, got, off[0])
+			}
+		}
+
+		if _, err := Count(data[:len(data)-1], opts...); err != ErrNotEnoughBytes {
+			t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+		}
+		if _, err := Offsets(data[:len(data)-1], opts...); err != ErrNotEnoughBytes {
+			t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+		}
+	}
+	if offsets, err := Offsets(nil); len(offsets) != 0 || offsets == nil || err != nil {
+		t.Errorf("expected no offsets; got %v, %v", offsets, err)
+	}
+}
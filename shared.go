@@ -0,0 +1,62 @@
+package byteblock
+
+import "io"
+
+// BlockTable is an immutable view of the blocks in a byte slice that
+// is safe for concurrent use, unlike ByteBlockSlicer, which carries a
+// cursor. The positions of all blocks are found once by
+// NewBlockTable, so that BlockAt fetches any block without touching
+// the others, and Slicer gives each goroutine its own cursor over the
+// same data.
+type BlockTable struct {
+	data    []byte
+	basePos int64   // position of data in the whole stream
+	headers []int64 // header positions within data, plus the end
+	opts    options
+}
+
+// NewBlockTable finds the blocks in data, reading only headers,
+// trailers and inline names as ByteBlockSlicer.Skip does. data must
+// not be modified while the table is in use.
+func NewBlockTable(data []byte, opts ...Option) (*BlockTable, error) {
+	slicer := NewByteBlockSlicer(data, opts...)
+	headers := []int64{}
+	for {
+		headerPos := slicer.numBytesSliced
+		_, _, _, err := slicer.sliceBlock(false)
+		if err == io.EOF {
+			headers = append(headers, headerPos)
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, headerPos)
+	}
+	return &BlockTable{data: slicer.data, basePos: slicer.basePos, headers: headers, opts: slicer.opts}, nil
+}
+
+// Len returns the number of blocks.
+func (t *BlockTable) Len() int {
+	return len(t.headers) - 1
+}
+
+// BlockAt returns the data of block i as Slice would, sharing memory
+// with the table unless the block is decompressed. Checksums are
+// verified, but WithOrderCheck is not, as blocks are fetched
+// independently. ErrIndexOutOfRange is returned if there is no such
+// block.
+func (t *BlockTable) BlockAt(i int) ([]byte, error) {
+	if i < 0 || i >= t.Len() {
+		return nil, ErrIndexOutOfRange
+	}
+	start, end := t.headers[i], t.headers[i+1]
+	slicer := &ByteBlockSlicer{data: t.data[start:end], basePos: t.basePos + start, numBlocks: int64(i), prevLength: -1, blocksLeft: -1, opts: t.opts}
+	return slicer.Slice()
+}
+
+// Slicer returns a new slicer positioned at the first block, for a
+// goroutine to walk the blocks in order.
+func (t *BlockTable) Slicer() *ByteBlockSlicer {
+	return &ByteBlockSlicer{data: t.data[:t.headers[t.Len()]], basePos: t.basePos, prevLength: -1, blocksLeft: -1, opts: t.opts}
+}
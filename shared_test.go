@@ -0,0 +1,72 @@
+package byteblock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestBlockTable(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithChecksums(), WithInlineNames(), WithMagicHeader()},
+		{WithCodec(CodecFlate), WithIndexFooter()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		var expected []string
+		for i := 0; i < 50; i++ {
+			block := fmt.Sprint("block ", i)
+			expected = append(expected, block)
+			writer.WriteString(block, 8)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		table, err := NewBlockTable(buf.Bytes(), opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if table.Len() != len(expected) {
+			t.Fatalf("expected %d blocks; got %d", len(expected), table.Len())
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range expected {
+					// Spread the goroutines over the blocks.
+					i = (i + g*7) % len(expected)
+					if data, err := table.BlockAt(i); string(data) != expected[i] || err != nil {
+						t.Errorf("block %d: expected %q; got %q, %v", i, expected[i], data, err)
+					}
+				}
+				slicer := table.Slicer()
+				for _, e := range expected {
+					if data, err := slicer.Slice(); string(data) != e || err != nil {
+						t.Errorf("expected %q; got %q, %v", e, data, err)
+					}
+				}
+				if _, err := slicer.Slice(); err != io.EOF {
+					t.Errorf("expected io.EOF; got %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		for _, i := range []int{-1, len(expected)} {
+			if _, err := table.BlockAt(i); err != ErrIndexOutOfRange {
+				t.Errorf("expected ErrIndexOutOfRange; got %v", err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	NewByteBlockWriter(&buf).WriteString("hello", 8)
+	if _, err := NewBlockTable(buf.Bytes()[:10]); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,44 @@
+package byteblock
+
+import "io"
+
+// Skip moves past the next block reading only its header and trailer,
+// so that the pages of its data are never touched. Unlike Slice, it
+// does not verify the checksum of the data nor the frame checksum, and
+// it does not count towards WithOrderCheck or MaxBlockSize. It
+// returns io.EOF if there are no more blocks.
+func (r *ByteBlockSlicer) Skip() error {
+	_, _, _, err := r.sliceBlock(false)
+	return err
+}
+
+// Skip moves past the next block without keeping its data. If the
+// underlying reader is an io.Seeker and neither WithChecksums nor
+// WithFrameChecksum is enabled, the data is skipped by seeking, in
+// which case a truncated last block may go unnoticed; otherwise the
+// data is read and the checksums are verified. It returns io.EOF if
+// there are no more blocks.
+func (r *ByteBlockReader) Skip() error {
+	h, err := r.readHeader()
+	if err != nil {
+		return err
+	}
+	seeker, ok := r.reader.(io.Seeker)
+	if ok && !r.opts.checksums && r.frameHash == nil {
+		if _, r.err = seeker.Seek(h.length, io.SeekCurrent); r.err != nil {
+			return r.err
+		}
+		r.numBytesRead += h.length
+		r.err = r.finishBlock(0)
+	} else {
+		var sum uint32
+		if sum, r.err = r.discardData(h.length); r.err == nil {
+			r.err = r.finishBlock(sum)
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	r.numBlocks++
+	return nil
+}
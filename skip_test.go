@@ -0,0 +1,72 @@
+package byteblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// readOnly hides any io.Seeker of the reader.
+type readOnly struct {
+	io.Reader
+}
+
+func TestSkip(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithChecksums(), WithInlineNames(), WithBackLink()},
+		{WithCodec(CodecFlate), WithFixedStride(256)},
+		{WithBlockKinds(), WithFrameChecksum()},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		for _, block := range []string{"first", "second", "third", "fourth"} {
+			writer.WriteString(block, 8)
+		}
+		if writer.opts.blockKinds {
+			writer.WriteEnd()
+			writer.WriteString("ignored", 8)
+		}
+		writer.Close()
+
+		slicer := NewByteBlockSlicer(buf.Bytes(), opts...)
+		seeking := NewByteBlockReader(bytes.NewReader(buf.Bytes()), opts...)
+		reading := NewByteBlockReader(readOnly{bytes.NewReader(buf.Bytes())}, opts...)
+		for _, skip := range []func() error{slicer.Skip, seeking.Skip, reading.Skip} {
+			if err := skip(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err := skip(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+		if data, err := slicer.Slice(); string(data) != "third" || err != nil {
+			t.Errorf("expected %q; got %q, %v", "third", data, err)
+		}
+		for _, reader := range []*ByteBlockReader{seeking, reading} {
+			if info, data, err := reader.ReadInfo(); string(data) != "third" || info.Index != 2 || err != nil {
+				t.Errorf("expected %q; got %+v, %q, %v", "third", info, data, err)
+			}
+		}
+		for _, skip := range []func() error{slicer.Skip, seeking.Skip, reading.Skip} {
+			if err := skip(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err := skip(); err != io.EOF {
+				t.Errorf("expected io.EOF; got %v", err)
+			}
+		}
+	}
+
+	// Skipping the slicer does not touch the data, while reading does.
+	var buf bytes.Buffer
+	NewByteBlockWriter(&buf, WithChecksums()).WriteString("hello", 8)
+	data := buf.Bytes()
+	data[16] ^= 1
+	if err := NewByteBlockSlicer(data, WithChecksums()).Skip(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := NewByteBlockReader(bytes.NewReader(data), WithChecksums()).Skip(); err != ErrChecksumMismatch {
+		t.Errorf("expected ErrChecksumMismatch; got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package byteblock
+
+import (
+	"errors"
+	"io"
+)
+
+// Segment describes a segment written by Split.
+type Segment struct {
+	// Index is the index of the segment, as given to create.
+	Index int
+	// FirstBlock is the index in the source stream of the first block
+	// of the segment.
+	FirstBlock int64
+	// NumBlocks is the number of blocks of the segment.
+	NumBlocks int64
+	// Size is the number of bytes of the segment.
+	Size int64
+}
+
+// Split reads the blocks of src and writes them out as a series of
+// streams, the segments, of at most maxSize bytes each, such as to
+// upload a big stream to an object store that limits the size of
+// objects. Blocks are never split across segments, and they are copied
+// as CopyBlock does, which keeps their alignment up to 4096. Each
+// segment is a complete stream written with opts, starting with its
+// own preamble with WithMagicHeader. create is called to open each
+// segment in turn, which is closed once complete. Split returns which
+// blocks landed in which segment; a stream without blocks yields no
+// segment. ErrSegmentTooSmall is returned if a block does not fit in
+// maxSize bytes on its own. The source cannot have an index footer, and
+// ErrSplitIndexFooter is returned with WithIndexFooter. With
+// WithStreamMAC, the MAC of src is verified, and each segment gets a
+// trailer of its own, which counts against maxSize.
+func Split(src io.Reader, maxSize int64, create func(segment int) (io.WriteCloser, error), opts ...Option) ([]Segment, error) {
+	o := newOptions(opts)
+	if o.indexFooter {
+		return nil, ErrSplitIndexFooter
+	}
+	var reserved int64 // for the trailer of WithStreamMAC
+	if o.macKey != nil {
+		reserved = o.macTrailerSize()
+	}
+	reader := NewByteBlockReader(src, opts...)
+	var segments []Segment
+	var writer *ByteBlockWriter
+	var file io.WriteCloser
+	// closeSegment finishes the current segment, if any.
+	closeSegment := func() error {
+		if writer == nil {
+			return nil
+		}
+		err := writer.Close()
+		segments[len(segments)-1].Size = writer.numBytesWritten
+		writer = nil
+		return err
+	}
+	dst := func(c *blockCopy) (*ByteBlockWriter, error) {
+		if writer != nil && writer.opts.namedBlockEnd(writer.numBytesWritten, c.align, c.info.Length, c.meta.name)+reserved <= maxSize {
+			segments[len(segments)-1].NumBlocks++
+			return writer, nil
+		}
+		if err := closeSegment(); err != nil {
+			return nil, err
+		}
+		index := len(segments)
+		f, err := create(index)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+		writer = NewByteBlockWriter(f, opts...)
+		writer.closer = f
+		segments = append(segments, Segment{Index: index, FirstBlock: c.info.Index, NumBlocks: 1})
+		if writer.opts.namedBlockEnd(writer.numBytesWritten, c.align, c.info.Length, c.meta.name)+reserved > maxSize {
+			return nil, ErrSegmentTooSmall
+		}
+		return writer, writer.err
+	}
+	for {
+		_, err := copyBlock(reader, dst)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if writer != nil {
+				file.Close()
+			}
+			return segments, err
+		}
+	}
+	return segments, closeSegment()
+}
+
+var (
+	ErrSegmentTooSmall  = errors.New("block does not fit in a segment")
+	ErrSplitIndexFooter = errors.New("cannot split a stream with an index footer")
+)
@@ -0,0 +1,77 @@
+package byteblock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestSplit(t *testing.T) {
+	opts := []Option{WithMagicHeader(), WithChecksums(), WithInlineNames()}
+	var src bytes.Buffer
+	writer := NewByteBlockWriter(&src, opts...)
+	var expected []string
+	for i := 0; i < 20; i++ {
+		block := strings.Repeat("x", i*10)
+		expected = append(expected, block)
+		writer.WriteNamedInline(fmt.Sprint(i), []byte(block), 64)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const maxSize = 512
+	dir := t.TempDir()
+	create := func(segment int) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(dir, fmt.Sprint(segment)))
+	}
+	segments, err := Split(bytes.NewReader(src.Bytes()), maxSize, create, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(segments) < 2 {
+		t.Fatalf("expected several segments; got %d", len(segments))
+	}
+	var next int64
+	for i, s := range segments {
+		if s.Index != i || s.FirstBlock != next || s.NumBlocks == 0 || s.Size > maxSize {
+			t.Errorf("unexpected segment %+v", s)
+		}
+		next += s.NumBlocks
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprint(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != s.Size {
+			t.Errorf("segment %d: expected %d bytes; got %d", i, s.Size, len(data))
+		}
+		slicer := NewByteBlockSlicer(data, opts...)
+		for j := s.FirstBlock; j < s.FirstBlock+s.NumBlocks; j++ {
+			name, block, err := slicer.SliceNamed()
+			if name != fmt.Sprint(j) || string(block) != expected[j] || err != nil {
+				t.Errorf("block %d: unexpected %q, %d bytes, %v", j, name, len(block), err)
+			}
+			if pos := uintptr(unsafe.Pointer(&block[:1][0])) - uintptr(unsafe.Pointer(&data[0])); pos%64 != 0 {
+				t.Errorf("block %d: expected alignment 64", j)
+			}
+		}
+		if _, err := slicer.Slice(); err != io.EOF {
+			t.Errorf("expected io.EOF; got %v", err)
+		}
+	}
+	if next != int64(len(expected)) {
+		t.Errorf("expected %d blocks; got %d", len(expected), next)
+	}
+
+	if _, err := Split(bytes.NewReader(src.Bytes()), 128, create, opts...); err != ErrSegmentTooSmall {
+		t.Errorf("expected ErrSegmentTooSmall; got %v", err)
+	}
+	if _, err := Split(bytes.NewReader(nil), maxSize, create, WithIndexFooter()); err != ErrSplitIndexFooter {
+		t.Errorf("expected ErrSplitIndexFooter; got %v", err)
+	}
+}
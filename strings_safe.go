@@ -0,0 +1,17 @@
+//go:build byteblock_nounsafe
+
+package byteblock
+
+// zeroCopyStrings is false with the byteblock_nounsafe tag: strings
+// and byte slices are copied instead of sharing memory.
+const zeroCopyStrings = false
+
+// stringBytes returns a copy of s.
+func stringBytes(s string) []byte {
+	return []byte(s)
+}
+
+// bytesString returns a copy of b.
+func bytesString(b []byte) string {
+	return string(b)
+}
@@ -0,0 +1,23 @@
+//go:build !byteblock_nounsafe
+
+package byteblock
+
+import "unsafe"
+
+// zeroCopyStrings tells whether strings share memory with byte slices
+// rather than being copied, see stringBytes and bytesString, and so do
+// the typed slices of SliceAs. Building with the byteblock_nounsafe tag
+// turns it off.
+const zeroCopyStrings = true
+
+// stringBytes returns a byte slice sharing memory with s. The result
+// must not be modified.
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// bytesString returns a string sharing memory with b, which must not
+// be modified afterwards.
+func bytesString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
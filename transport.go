@@ -0,0 +1,33 @@
+package byteblock
+
+import "io"
+
+// transportOptions returns the options of the transport profile, see
+// NewTransportWriter.
+func transportOptions() []Option {
+	return []Option{WithBigEndian(), WithMagicHeader(), WithChecksums()}
+}
+
+// NewTransportWriter creates a ByteBlockWriter for a wire protocol,
+// with the conventional choices bundled: big-endian headers and
+// trailers (WithBigEndian), a preamble recording the format version
+// and options (WithMagicHeader), and a CRC-32C checksum per block
+// (WithChecksums). Peers using NewTransportSlicer or
+// NewTransportReader read the stream without further configuration,
+// and the preamble makes any other reader fail with ErrFormatMismatch
+// rather than misread it.
+func NewTransportWriter(w io.Writer) *ByteBlockWriter {
+	return NewByteBlockWriter(w, transportOptions()...)
+}
+
+// NewTransportSlicer creates a slicer over data written by a
+// NewTransportWriter.
+func NewTransportSlicer(data []byte) *ByteBlockSlicer {
+	return NewByteBlockSlicer(data, transportOptions()...)
+}
+
+// NewTransportReader creates a streaming reader of a stream written by
+// a NewTransportWriter, such as from a network connection.
+func NewTransportReader(r io.Reader) *ByteBlockReader {
+	return NewByteBlockReader(r, transportOptions()...)
+}
@@ -0,0 +1,61 @@
+package byteblock
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	blocks := []string{"hello", "", "world"}
+	var buf bytes.Buffer
+	writer := NewTransportWriter(&buf)
+	for _, b := range blocks {
+		writer.WriteString(b, 8)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := buf.Bytes()
+
+	// The length of the first block is big-endian.
+	if length := binary.BigEndian.Uint64(data[preambleSize:]); length != 5 {
+		t.Errorf("expected a big-endian length of 5; got %d", length)
+	}
+	if f, err := Probe(bytes.NewReader(data)); !f.BigEndian || !f.Checksums || err != nil {
+		t.Errorf("expected a big-endian format with checksums; got %+v, %v", f, err)
+	}
+
+	slicer := NewTransportSlicer(data)
+	reader := NewTransportReader(bytes.NewReader(data))
+	for _, expected := range blocks {
+		if block, err := slicer.Slice(); string(block) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, block, err)
+		}
+		if block, err := reader.Read(); string(block) != expected || err != nil {
+			t.Errorf("expected %q; got %q, %v", expected, block, err)
+		}
+	}
+	if _, err := slicer.Slice(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	// Corrupted data is caught by the checksums.
+	corrupted := bytes.Clone(data)
+	corrupted[bytes.Index(corrupted, []byte("world"))] ^= 1
+	slicer = NewTransportSlicer(corrupted)
+	slicer.Slice()
+	slicer.Slice()
+	if _, err := slicer.Slice(); err != ErrChecksumMismatch {
+		t.Errorf("expected ErrChecksumMismatch; got %v", err)
+	}
+
+	// A peer configured otherwise is caught by the preamble.
+	if _, err := NewByteBlockSlicer(data, WithMagicHeader(), WithChecksums()).Slice(); err != ErrFormatMismatch {
+		t.Errorf("expected ErrFormatMismatch; got %v", err)
+	}
+}
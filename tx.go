@@ -0,0 +1,102 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// transaction is the state of the writer saved by Begin.
+type transaction struct {
+	writer          io.Writer
+	buf             bytes.Buffer
+	numBytesWritten int64
+	numPaddingBytes int64
+	numBlocks       int64
+	numIndexEntries int
+}
+
+// Begin starts a transaction: the blocks written until Commit are kept
+// in memory, and only reach the underlying writer together at Commit,
+// so that a group of blocks forming one logical record either all
+// appear or none do. Rollback drops them instead. The current block
+// must be finished; otherwise ErrBlockNotFinished is returned.
+// Transactions do not nest.
+func (w *ByteBlockWriter) Begin() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.tx != nil {
+		return ErrTransactionActive
+	}
+	if w.numBytesLeft > 0 {
+		return ErrBlockNotFinished
+	}
+	w.tx = &transaction{
+		writer:          w.writer,
+		numBytesWritten: w.numBytesWritten,
+		numPaddingBytes: w.numPaddingBytes,
+		numBlocks:       w.numBlocks,
+		numIndexEntries: len(w.index),
+	}
+	w.writer = &w.tx.buf
+	return nil
+}
+
+// Commit writes the blocks of the current transaction to the
+// underlying writer in a single call, and ends the transaction. The
+// current block must be finished; otherwise ErrBlockNotFinished is
+// returned.
+func (w *ByteBlockWriter) Commit() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.tx == nil {
+		return ErrNoTransaction
+	}
+	if w.numBytesLeft > 0 {
+		return ErrBlockNotFinished
+	}
+	tx := w.tx
+	w.tx = nil
+	w.writer = tx.writer
+	if _, w.err = w.writer.Write(tx.buf.Bytes()); w.err != nil {
+		return w.err
+	}
+	if w.mac != nil {
+		w.mac.Write(tx.buf.Bytes())
+	}
+	if w.flusher != nil {
+		w.err = w.flusher.Flush()
+	}
+	return w.err
+}
+
+// Rollback drops the blocks of the current transaction, including any
+// unfinished block, and ends the transaction. The writer is left as it
+// was at Begin, which also clears errors met during the transaction.
+func (w *ByteBlockWriter) Rollback() error {
+	if w.tx == nil {
+		return ErrNoTransaction
+	}
+	tx := w.tx
+	w.tx = nil
+	w.writer = tx.writer
+	w.numBytesWritten = tx.numBytesWritten
+	w.numPaddingBytes = tx.numPaddingBytes
+	w.numBlocks = tx.numBlocks
+	if w.index != nil {
+		w.index = w.index[:tx.numIndexEntries]
+	}
+	w.numBytesLeft = 0
+	w.inBlock = false
+	w.pending = nil
+	w.deferred = false
+	w.err = nil
+	return nil
+}
+
+var (
+	ErrTransactionActive = errors.New("a transaction is active")
+	ErrNoTransaction     = errors.New("no transaction is active")
+)
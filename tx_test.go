@@ -0,0 +1,65 @@
+package byteblock
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithIndexFooter())
+	writer.WriteString("before", 8)
+
+	if err := writer.Begin(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writer.WriteString("dropped", 8)
+	writer.NewBlock(8, 10)
+	writer.AppendString("too long for the block")
+	if buf.Len() != 22 {
+		t.Errorf("expected nothing written during the transaction; got %d bytes", buf.Len())
+	}
+	if err := writer.Commit(); err != ErrWriteMoreThanRequested {
+		t.Errorf("expected ErrWriteMoreThanRequested; got %v", err)
+	}
+	if err := writer.Rollback(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	writer.Begin()
+	if err := writer.Begin(); err != ErrTransactionActive {
+		t.Errorf("expected ErrTransactionActive; got %v", err)
+	}
+	writer.WriteString("first", 64)
+	writer.WriteString("second", 8)
+	if err := writer.Close(); err != ErrTransactionActive {
+		t.Errorf("expected ErrTransactionActive; got %v", err)
+	}
+	if err := writer.Commit(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := writer.Commit(); err != ErrNoTransaction {
+		t.Errorf("expected ErrNoTransaction; got %v", err)
+	}
+	writer.WriteString("after", 8)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithIndexFooter())
+	var got []string
+	for data, err := range slicer.All() {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, string(data))
+	}
+	if expected := []string{"before", "first", "second", "after"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+	entries, err := LoadIndex(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(entries) != 4 {
+		t.Errorf("expected 4 index entries; got %v, %v", entries, err)
+	}
+}
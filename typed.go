@@ -0,0 +1,154 @@
+package byteblock
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// SliceAsSlice slices the next block out of r and reinterprets its
+// data as a []T without copying. The block length must be a multiple
+// of the size of T, and the block data must be suitably aligned in
+// memory for T; otherwise ErrElemSize or ErrMisaligned is returned and
+// the block is still consumed.
+//
+// T is restricted to Numeric types, since reinterpreting bytes as
+// pointers, slices, strings, maps or interfaces is unsafe. The values
+// are read in the native byte order and layout of the machine, so the
+// stream must have been written on a compatible machine. The returned
+// slice shares memory with the backing data of r: it is only valid as
+// long as that memory is (in particular, until a memory-mapped file is
+// unmapped), and modifying either one modifies the other. Writing the
+// block with an alignment that is a multiple of unsafe.Alignof(T),
+// over backing data that is itself aligned, satisfies the alignment
+// requirement. With the byteblock_nounsafe build tag, the data is
+// copied instead, see SliceAs.
+func SliceAsSlice[T Numeric](r *ByteBlockSlicer) ([]T, error) {
+	data, err := r.Slice()
+	if err != nil {
+		return nil, err
+	}
+	return SliceAs[T](data)
+}
+
+// Numeric is the set of fixed-size numeric types that WriteSlice,
+// SliceAs and SliceAsSlice accept.
+type Numeric interface {
+	~int8 | ~int16 | ~int32 | ~int64 |
+		~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64 | ~complex64 | ~complex128
+}
+
+// WriteSlice writes the values of items as one block, in the native
+// byte order of the machine, so that SliceAsSlice or SliceAs can read
+// them back without copying. A non-positive align is replaced by the
+// size of T, which keeps the values aligned in memory when the backing
+// data of the reader is. With the byteblock_nounsafe build tag, the
+// values are copied before being written.
+func WriteSlice[T Numeric](w *ByteBlockWriter, items []T, align int64) error {
+	var zero T
+	if align <= 0 {
+		align = int64(binary.Size(zero))
+	}
+	if len(items) == 0 {
+		return w.Write(nil, align)
+	}
+	return w.Write(numericBytes(items), align)
+}
+
+// SliceAs reinterprets data, such as a block from Slice, as a []T
+// without copying, with the same requirements and caveats as
+// SliceAsSlice: the length of data must be a multiple of the size of
+// T, and data must be suitably aligned in memory for T; otherwise
+// ErrElemSize or ErrMisaligned is returned. With the
+// byteblock_nounsafe build tag, the values are decoded into a new
+// slice with encoding/binary instead, which needs no alignment.
+func SliceAs[T Numeric](data []byte) ([]T, error) {
+	return sliceAs[T](data)
+}
+
+// WriteStrings writes items as one block with the given alignment.
+// Each string is stored with an int64 length prefix, so that an empty
+// slice and a slice of empty strings are told apart.
+func (w *ByteBlockWriter) WriteStrings(items []string, align int64) error {
+	var length int64
+	for _, item := range items {
+		length += 8 + int64(len(item))
+	}
+	if err := w.NewBlock(align, length); err != nil {
+		return err
+	}
+	for _, item := range items {
+		w.fillStub(int64(len(item)))
+		if err := w.append(w.stub[:]); err != nil {
+			return err
+		}
+		if err := w.append(stringBytes(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SliceStrings slices the next block, written by WriteStrings, and
+// decodes it into a newly allocated slice of strings.
+func (r *ByteBlockSlicer) SliceStrings() ([]string, error) {
+	data, err := r.Slice()
+	if err != nil {
+		return nil, err
+	}
+	items := []string{}
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, ErrNotEnoughBytes
+		}
+		n := readInt64(data)
+		data = data[8:]
+		if n < 0 || n > int64(len(data)) {
+			return nil, ErrNotEnoughBytes
+		}
+		items = append(items, string(data[:n]))
+		data = data[n:]
+	}
+	return items, nil
+}
+
+// WriteKV writes a key/value pair as one block with the given
+// alignment. The key is stored with an int64 length prefix and
+// followed by the value.
+func (w *ByteBlockWriter) WriteKV(key, value []byte, align int64) error {
+	if err := w.NewBlock(align, 8+int64(len(key))+int64(len(value))); err != nil {
+		return err
+	}
+	w.fillStub(int64(len(key)))
+	if err := w.append(w.stub[:]); err != nil {
+		return err
+	}
+	if err := w.append(key); err != nil {
+		return err
+	}
+	return w.append(value)
+}
+
+// SliceKV slices the next block, written by WriteKV, and splits it into
+// the key and the value. Both are sliced out of the backing data
+// slice.
+func (r *ByteBlockSlicer) SliceKV() (key, value []byte, err error) {
+	data, err := r.Slice()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < 8 {
+		return nil, nil, ErrNotEnoughBytes
+	}
+	n := readInt64(data)
+	data = data[8:]
+	if n < 0 || n > int64(len(data)) {
+		return nil, nil, ErrNotEnoughBytes
+	}
+	return data[:n], data[n:], nil
+}
+
+var (
+	ErrElemSize   = errors.New("block length is not a multiple of the element size")
+	ErrMisaligned = errors.New("block data is misaligned for the element type")
+)
@@ -0,0 +1,25 @@
+//go:build byteblock_nounsafe
+
+package byteblock
+
+import "encoding/binary"
+
+// numericBytes returns a copy of items in the native byte order.
+func numericBytes[T Numeric](items []T) []byte {
+	b, _ := binary.Append(nil, binary.NativeEndian, items)
+	return b
+}
+
+// sliceAs returns a copy of data decoded as a []T, see SliceAs.
+func sliceAs[T Numeric](data []byte) ([]T, error) {
+	var zero T
+	size := binary.Size(zero)
+	if size <= 0 || len(data)%size != 0 {
+		return nil, ErrElemSize
+	}
+	items := make([]T, len(data)/size)
+	if _, err := binary.Decode(data, binary.NativeEndian, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
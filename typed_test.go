@@ -0,0 +1,156 @@
+package byteblock
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestSliceAsSlice(t *testing.T) {
+	values := []int32{1, -2, 3, 1 << 30, -1 << 31}
+	raw := unsafe.Slice((*byte)(unsafe.Pointer(&values[0])), len(values)*4)
+
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.Write(raw, 4)
+	writer.Write(nil, 4)
+	writer.Write([]byte("x"), 0)
+	writer.Write(raw, 1) // Starts right after the header at an odd position.
+	writer.Write(raw[:6], 4)
+
+	// Copy into memory that is at least 8-byte aligned.
+	backing := make([]int64, buf.Len()/8+1)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&backing[0])), buf.Len())
+	copy(data, buf.Bytes())
+
+	slicer := NewByteBlockSlicer(data)
+	got, err := SliceAsSlice[int32](slicer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("expected %v; got %v", values, got)
+	}
+	// The view shares memory with the backing data, unless built with
+	// byteblock_nounsafe.
+	if start := slicer.numBytesSliced - int64(len(raw)); zeroCopyStrings && unsafe.Pointer(&got[0]) != unsafe.Pointer(&data[start]) {
+		t.Errorf("expected a view over the backing data")
+	}
+
+	if got, err := SliceAsSlice[int32](slicer); len(got) != 0 || err != nil {
+		t.Errorf("expected empty slice; got %v, %v", got, err)
+	}
+	if _, err := SliceAsSlice[int32](slicer); err != ErrElemSize {
+		t.Errorf("expected ErrElemSize; got %v", err)
+	}
+	// A copy needs no alignment.
+	if got, err := SliceAsSlice[int32](slicer); zeroCopyStrings && err != ErrMisaligned {
+		t.Errorf("expected ErrMisaligned; got %v", err)
+	} else if !zeroCopyStrings && (!reflect.DeepEqual(got, values) || err != nil) {
+		t.Errorf("expected %v; got %v, %v", values, got, err)
+	}
+	if _, err := SliceAsSlice[int32](slicer); err != ErrElemSize {
+		t.Errorf("expected ErrElemSize; got %v", err)
+	}
+}
+
+func TestWriteSlice(t *testing.T) {
+	embedding := []float64{0.5, -1.25, 3e100}
+	counts := []uint16{1, 2, 65535}
+
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	writer.Write([]byte("x"), 1)
+	WriteSlice(writer, embedding, 0)
+	WriteSlice(writer, counts, 0)
+	WriteSlice[int32](writer, nil, 0)
+
+	// Copy into memory that is at least 8-byte aligned.
+	backing := make([]int64, buf.Len()/8+1)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&backing[0])), buf.Len())
+	copy(data, buf.Bytes())
+
+	slicer := NewByteBlockSlicer(data)
+	slicer.Slice()
+	block, _ := slicer.Slice()
+	if got, err := SliceAs[float64](block); !reflect.DeepEqual(got, embedding) || err != nil {
+		t.Errorf("expected %v; got %v, %v", embedding, got, err)
+	}
+	block, _ = slicer.Slice()
+	if got, err := SliceAs[uint16](block); !reflect.DeepEqual(got, counts) || err != nil {
+		t.Errorf("expected %v; got %v, %v", counts, got, err)
+	}
+	if got, err := SliceAsSlice[int32](slicer); len(got) != 0 || err != nil {
+		t.Errorf("expected an empty slice; got %v, %v", got, err)
+	}
+
+	if _, err := SliceAs[float64](data[:12]); err != ErrElemSize {
+		t.Errorf("expected ErrElemSize; got %v", err)
+	}
+	if _, err := SliceAs[float64](data[1:17]); zeroCopyStrings && err != ErrMisaligned || !zeroCopyStrings && err != nil {
+		t.Errorf("expected ErrMisaligned unless copying; got %v", err)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	items := [][]string{
+		{"hello", "", "world", "a much longer string than the others"},
+		{},
+		{""},
+		{"", ""},
+		{"single"},
+	}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, i := range items {
+		if err := writer.WriteStrings(i, 8); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, i := range items {
+		if got, err := slicer.SliceStrings(); !reflect.DeepEqual(got, i) || err != nil {
+			t.Errorf("expected %q; got %q, %v", i, got, err)
+		}
+	}
+
+	buf.Reset()
+	NewByteBlockWriter(&buf).Write([]byte{5, 0, 0, 0, 0, 0, 0, 0, 'a'}, 0)
+	if _, err := NewByteBlockSlicer(buf.Bytes()).SliceStrings(); err != ErrNotEnoughBytes {
+		t.Errorf("expected ErrNotEnoughBytes; got %v", err)
+	}
+}
+
+func TestKV(t *testing.T) {
+	pairs := []struct {
+		Key, Value string
+	}{
+		{"name", "byteblock"}, {"empty", ""}, {"", "no key"}, {"", ""}, {"k", "v"},
+	}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf)
+	for _, p := range pairs {
+		if err := writer.WriteKV([]byte(p.Key), []byte(p.Value), 8); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	slicer := NewByteBlockSlicer(buf.Bytes())
+	for _, p := range pairs {
+		key, value, err := slicer.SliceKV()
+		if string(key) != p.Key || string(value) != p.Value || err != nil {
+			t.Errorf("expected %+v; got %q, %q, %v", p, key, value, err)
+		}
+	}
+
+	buf.Reset()
+	writer = NewByteBlockWriter(&buf)
+	writer.Write([]byte{1, 2, 3}, 0)
+	writer.Write([]byte{5, 0, 0, 0, 0, 0, 0, 0, 'a'}, 0)
+	slicer = NewByteBlockSlicer(buf.Bytes())
+	for i := 0; i < 2; i++ {
+		if _, _, err := slicer.SliceKV(); err != ErrNotEnoughBytes {
+			t.Errorf("block %d: expected ErrNotEnoughBytes; got %v", i, err)
+		}
+	}
+}
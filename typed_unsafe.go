@@ -0,0 +1,28 @@
+//go:build !byteblock_nounsafe
+
+package byteblock
+
+import "unsafe"
+
+// numericBytes returns a byte slice sharing memory with items.
+func numericBytes[T Numeric](items []T) []byte {
+	var zero T
+	return unsafe.Slice((*byte)(unsafe.Pointer(&items[0])), len(items)*int(unsafe.Sizeof(zero)))
+}
+
+// sliceAs returns a []T sharing memory with data, see SliceAs.
+func sliceAs[T Numeric](data []byte) ([]T, error) {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	if size == 0 || len(data)%size != 0 {
+		return nil, ErrElemSize
+	}
+	if len(data) == 0 {
+		return []T{}, nil
+	}
+	p := unsafe.Pointer(&data[0])
+	if uintptr(p)%unsafe.Alignof(zero) != 0 {
+		return nil, ErrMisaligned
+	}
+	return unsafe.Slice((*T)(p), len(data)/size), nil
+}
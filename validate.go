@@ -0,0 +1,123 @@
+package byteblock
+
+import (
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// Validate reads the whole stream from r and checks that it is made of
+// well-formed blocks up to its very end: every header must be valid and
+// every block must fit in the stream, with its checksums, back link
+// and compressed data as enabled by opts verified; nothing may follow
+// an end marker. It returns the number of blocks. The first problem is
+// reported as a *BlockError giving the index and the position of the
+// offending block, which wraps the cause, such as ErrNotEnoughBytes for
+// a truncated stream or ErrTrailingData. With WithStreamMAC, the MAC
+// of the stream is verified at its end. The data is streamed, so
+// arbitrarily large blocks are fine.
+func Validate(r io.Reader, opts ...Option) (blocks int64, err error) {
+	reader := NewByteBlockReader(r, opts...)
+	for ; ; blocks++ {
+		br, err := reader.NextReader()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			_, err = io.Copy(io.Discard, br)
+		}
+		if err == nil {
+			// A decompressor may stop short of the end of the block.
+			err = reader.skipBlock()
+		}
+		if err != nil {
+			return blocks, &BlockError{Index: int(blocks), Offset: reader.blockPos, Err: err}
+		}
+	}
+	// An end marker stops the reader before the end of the stream.
+	var b [1]byte
+	if n, err := io.ReadFull(reader.reader, b[:]); n > 0 {
+		return blocks, &BlockError{Index: int(blocks), Offset: reader.numBytesRead, Err: ErrTrailingData}
+	} else if err != io.EOF {
+		// Such as the MAC of WithStreamMAC not matching.
+		return blocks, &BlockError{Index: int(blocks), Offset: reader.numBytesRead, Err: err}
+	}
+	return blocks, nil
+}
+
+// VerifyStreamBlocks reads the stream from r, written with
+// WithChecksums and the other options in opts, and verifies the
+// checksum of every block as it goes, so that a huge stream can be
+// checked from a pipe with constant memory. It returns the number of
+// blocks, or the index of the first block that fails along with a
+// *BlockError wrapping the cause, such as ErrChecksumMismatch. Block
+// data is neither decompressed nor opened.
+func VerifyStreamBlocks(r io.Reader, opts ...Option) (int, error) {
+	reader := NewByteBlockReader(r, append(opts[:len(opts):len(opts)], WithChecksums())...)
+	blocks, err := reader.Drain()
+	if err != nil {
+		return blocks, &BlockError{Index: blocks, Offset: reader.blockPos, Err: err}
+	}
+	return blocks, nil
+}
+
+// RepairChecksums writes the stream in src, written with WithChecksums
+// and the other options in opts, to dst with the checksum of every
+// block computed afresh from its data, which is trusted as is. It
+// returns the number of blocks whose stored checksum differed. Blocks
+// keep their header, padding and thus their position, and their data
+// is neither decompressed nor opened; the frame checksums, the index
+// footer and the stream MAC enabled by opts are written anew as well.
+func RepairChecksums(dst io.Writer, src []byte, opts ...Option) (fixed int, err error) {
+	opts = append(opts[:len(opts):len(opts)], WithChecksums())
+	slicer := NewByteBlockSlicer(src, opts...)
+	writer := NewByteBlockWriter(dst, opts...)
+	o := &slicer.opts
+	if o.countHeader && len(src) > 0 && slicer.err == nil {
+		if err := writer.WriteCountHeader(slicer.count); err != nil {
+			return fixed, err
+		}
+	}
+	for {
+		headerPos := slicer.numBytesSliced
+		h, _, data, err := slicer.sliceBlock(false)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fixed, err
+		}
+		sum := slicer.data[slicer.numBytesSliced-o.trailerSize():]
+		if o.readField(sum) != int64(crc32.Checksum(data, castagnoliTable)) {
+			fixed++
+		}
+		meta := blockMeta{flags: h.flags, raw: true}
+		if o.inlineNames {
+			var name []byte
+			if name, data, err = splitInlineName(data); err != nil {
+				return fixed, err
+			}
+			meta.name = string(name)
+		}
+		align := int64(1)
+		if o.offsetMode == offsetAlign {
+			_, offsetPos := o.fieldPos()
+			align = o.readField(slicer.data[headerPos+int64(offsetPos):])
+		}
+		// The header is written as is, rather than aligned again.
+		if err := writer.checkNewBlock(int64(len(data))); err != nil {
+			return fixed, err
+		}
+		if err := writer.writeHeader(int64(len(data)), align, h.padding, meta); err != nil {
+			return fixed, err
+		}
+		if len(data) > 0 {
+			if err := writer.append(data); err != nil {
+				return fixed, err
+			}
+		}
+	}
+	return fixed, writer.Close()
+}
+
+var ErrTrailingData = errors.New("data after the end of the stream")
@@ -0,0 +1,119 @@
+package byteblock
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	opts := []Option{WithChecksums(), WithCodec(CodecFlate), WithInlineNames(), WithBlockKinds()}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, opts...)
+	writer.WriteString("hello", 8)
+	writer.WriteNamedInline("second", bytes.Repeat([]byte("abc"), 1000), 8)
+	offset := writer.NextHeaderOffset()
+	writer.WriteString("third", 8)
+	end := writer.NextHeaderOffset()
+	writer.WriteEnd()
+	writer.Close()
+	data := buf.Bytes()
+
+	if n, err := Validate(bytes.NewReader(data), opts...); n != 3 || err != nil {
+		t.Errorf("expected 3 blocks; got %d, %v", n, err)
+	}
+
+	for _, i := range []struct {
+		Name   string
+		Data   []byte
+		Index  int
+		Offset int64
+		Err    error
+	}{
+		{"truncated", data[:end-1], 2, offset, ErrNotEnoughBytes},
+		{"corrupted", append(append([]byte(nil), data[:end-9]...), data[end-9]^1), 2, offset, ErrChecksumMismatch},
+		{"trailing", append(append([]byte(nil), data...), 0), 3, int64(len(data)), ErrTrailingData},
+	} {
+		if i.Name == "corrupted" {
+			i.Data = append(i.Data, data[end-8:]...)
+		}
+		n, err := Validate(bytes.NewReader(i.Data), opts...)
+		var blockErr *BlockError
+		if n != int64(i.Index) || !errors.As(err, &blockErr) || blockErr.Index != i.Index || blockErr.Offset != i.Offset || !errors.Is(err, i.Err) {
+			t.Errorf("%s: expected %v at block %d, offset %d; got %d, %v", i.Name, i.Err, i.Index, i.Offset, n, err)
+		}
+	}
+}
+
+func TestVerifyStreamBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithChecksums())
+	var offsets []int64
+	for i := 0; i < 5; i++ {
+		offsets = append(offsets, writer.NextHeaderOffset())
+		writer.WriteString(strings.Repeat("x", i*10), 8)
+	}
+	writer.Close()
+	data := buf.Bytes()
+	if n, err := VerifyStreamBlocks(bytes.NewReader(data)); n != 5 || err != nil {
+		t.Errorf("expected 5 blocks; got %d, %v", n, err)
+	}
+
+	// Corrupt the data of block 3.
+	corrupted := bytes.Clone(data)
+	corrupted[offsets[4]-9] ^= 1
+	n, err := VerifyStreamBlocks(bytes.NewReader(corrupted))
+	var blockErr *BlockError
+	if n != 3 || !errors.As(err, &blockErr) || blockErr.Index != 3 || blockErr.Offset != offsets[3] || !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch at block 3, offset %d; got %d, %v", offsets[3], n, err)
+	}
+}
+
+func TestRepairChecksums(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithChecksums()},
+		{WithChecksums(), WithInlineNames(), WithFrameChecksum(), WithIndexFooter()},
+		{WithChecksums(), WithAlignInHeader(), WithMagicHeader(), WithCodec(CodecGzip)},
+	} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, opts...)
+		var ends []int64
+		for i := 0; i < 5; i++ {
+			writer.WriteString(strings.Repeat("x", i*10), 16)
+			ends = append(ends, writer.NextHeaderOffset())
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data := buf.Bytes()
+		o := newOptions(opts)
+		trailer := o.trailerSize()
+
+		var repaired bytes.Buffer
+		if fixed, err := RepairChecksums(&repaired, data, opts...); fixed != 0 || err != nil {
+			t.Errorf("expected 0 checksums fixed; got %d, %v", fixed, err)
+		}
+		if !bytes.Equal(repaired.Bytes(), data) {
+			t.Errorf("expected an intact stream to be unchanged")
+		}
+
+		// Break the checksums of blocks 1 and 3.
+		broken := bytes.Clone(data)
+		broken[ends[1]-trailer] ^= 1
+		broken[ends[3]-trailer+3] ^= 0xff
+		if _, err := VerifyStreamBlocks(bytes.NewReader(broken), opts...); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("expected ErrChecksumMismatch; got %v", err)
+		}
+		repaired.Reset()
+		if fixed, err := RepairChecksums(&repaired, broken, opts...); fixed != 2 || err != nil {
+			t.Errorf("expected 2 checksums fixed; got %d, %v", fixed, err)
+		}
+		if !bytes.Equal(repaired.Bytes(), data) {
+			t.Errorf("expected the original stream back")
+		}
+		if _, err := VerifyStreamBlocks(bytes.NewReader(repaired.Bytes()), opts...); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
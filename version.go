@@ -0,0 +1,36 @@
+package byteblock
+
+import "errors"
+
+// NewBlockVer is like NewBlock except that it also records version in
+// the block header. The writer must have been created with
+// WithBlockVersions; otherwise ErrBlockVersionsDisabled is returned.
+func (w *ByteBlockWriter) NewBlockVer(version uint8, align, length int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.opts.blockVersions {
+		w.err = ErrBlockVersionsDisabled
+		return w.err
+	}
+	return w.newBlock(align, length, blockMeta{flags: uint64(version)})
+}
+
+// SliceVer is like Slice except that it also returns the version
+// recorded by NewBlockVer. The slicer must have been created with
+// WithBlockVersions; otherwise ErrBlockVersionsDisabled is returned.
+func (r *ByteBlockSlicer) SliceVer() ([]byte, uint8, error) {
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	if !r.opts.blockVersions {
+		return nil, 0, ErrBlockVersionsDisabled
+	}
+	h, _, data, err := r.slice()
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, h.version(), nil
+}
+
+var ErrBlockVersionsDisabled = errors.New("block versions are not enabled")
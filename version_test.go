@@ -0,0 +1,72 @@
+package byteblock
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlockVersions(t *testing.T) {
+	blocks := []struct {
+		Data    string
+		Version uint8
+	}{
+		{"old", 1}, {"new", 2}, {"plain", 0}, {"old again", 1}, {"max", 255},
+	}
+	var buf bytes.Buffer
+	writer := NewByteBlockWriter(&buf, WithBlockVersions())
+	for i, b := range blocks {
+		if i == 2 {
+			writer.WriteString(b.Data, 16)
+			continue
+		}
+		if err := writer.NewBlockVer(b.Version, 16, int64(len(b.Data))); err != nil {
+			t.Fatalf("block %d: unexpected error: %v", i, err)
+		}
+		writer.AppendString(b.Data)
+		if start := buf.Len() - len(b.Data); start%16 != 0 {
+			t.Errorf("block %d: misaligned data starting at %d", i, start)
+		}
+	}
+
+	slicer := NewByteBlockSlicer(buf.Bytes(), WithBlockVersions())
+	for i, b := range blocks {
+		data, version, err := slicer.SliceVer()
+		if string(data) != b.Data || version != b.Version || err != nil {
+			t.Errorf("block %d: expected %+v; got %q, %d, %v", i, b, data, version, err)
+		}
+	}
+	reader := NewByteBlockReader(bytes.NewReader(buf.Bytes()), WithBlockVersions())
+	for i, b := range blocks {
+		if data, err := reader.Read(); string(data) != b.Data || err != nil {
+			t.Errorf("block %d: expected %q; got %q, %v", i, b.Data, data, err)
+		}
+	}
+
+	if err := NewByteBlockWriter(&buf).NewBlockVer(1, 0, 0); err != ErrBlockVersionsDisabled {
+		t.Errorf("expected ErrBlockVersionsDisabled; got %v", err)
+	}
+	if _, _, err := NewByteBlockSlicer(buf.Bytes()).SliceVer(); err != ErrBlockVersionsDisabled {
+		t.Errorf("expected ErrBlockVersionsDisabled; got %v", err)
+	}
+
+	// The flags field shifts the data, which the other header
+	// interpretations must account for.
+	for _, opt := range []Option{WithAbsoluteOffset(), WithAlignInHeader(), WithInlineNames()} {
+		var buf bytes.Buffer
+		writer := NewByteBlockWriter(&buf, WithBlockVersions(), opt)
+		writer.NewBlockVer(3, 32, 5)
+		writer.AppendString("hello")
+		writer.NewBlockAt(0x100, 5)
+		writer.AppendString("world")
+		slicer := NewByteBlockSlicer(buf.Bytes(), WithBlockVersions(), opt)
+		if data, version, err := slicer.SliceVer(); string(data) != "hello" || version != 3 || err != nil {
+			t.Errorf("expected hello version 3; got %q, %d, %v", data, version, err)
+		}
+		if data, version, err := slicer.SliceVer(); string(data) != "world" || version != 0 || err != nil {
+			t.Errorf("expected world version 0; got %q, %d, %v", data, version, err)
+		}
+		if slicer.numBytesSliced != 0x105 {
+			t.Errorf("expected world at 0x100; ends at %#x", slicer.numBytesSliced)
+		}
+	}
+}